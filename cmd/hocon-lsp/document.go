@@ -0,0 +1,250 @@
+package main
+
+import (
+    "fmt"
+    "net/url"
+    "path/filepath"
+    "sort"
+    "strings"
+    "unicode/utf8"
+
+    "github.com/liyinhgqw/typesafe-config/parse"
+)
+
+// document is an open file and what the server knows about it.
+type document struct {
+    uri   string
+    path  string
+    text  string
+    lines []int // the offsets of the starts of the lines
+
+    symbols  []*parse.Symbol
+    refs     []*parse.Reference
+    conf     *parse.Config // the config as written
+    resolved *parse.Config // the resolved config with its fallback, or nil
+}
+
+func newDocument(uri, text string) *document {
+    d := &document{uri: uri, path: uriPath(uri), text: text, lines: []int{0}}
+    for i := 0; i < len(text); i++ {
+        if text[i] == '\n' {
+            d.lines = append(d.lines, i+1)
+        }
+    }
+    d.symbols, d.refs = parse.Outline(d.path, text)
+    return d
+}
+
+// uriPath returns the file path of a file URI, or the URI itself if it is
+// not one.
+func uriPath(uri string) string {
+    u, err := url.Parse(uri)
+    if err != nil || u.Scheme != "file" {
+        return uri
+    }
+    return filepath.FromSlash(u.Path)
+}
+
+// pathURI returns the file URI of path.
+func pathURI(path string) string {
+    if abs, err := filepath.Abs(path); err == nil {
+        path = abs
+    }
+    u := url.URL{Scheme: "file", Path: filepath.ToSlash(path)}
+    return u.String()
+}
+
+// position returns the LSP position of the byte offset off, whose
+// character is counted in UTF-16 code units.
+func (d *document) position(off parse.Pos) position {
+    o := int(off)
+    if o > len(d.text) {
+        o = len(d.text)
+    }
+    line := sort.Search(len(d.lines), func(i int) bool { return d.lines[i] > o }) - 1
+    char := 0
+    for _, r := range d.text[d.lines[line]:o] {
+        char += utf16Len(r)
+    }
+    return position{line, char}
+}
+
+// offset returns the byte offset of the LSP position p.
+func (d *document) offset(p position) parse.Pos {
+    if p.Line < 0 {
+        return 0
+    }
+    if p.Line >= len(d.lines) {
+        return parse.Pos(len(d.text))
+    }
+    o := d.lines[p.Line]
+    for char := 0; char < p.Character && o < len(d.text) && d.text[o] != '\n'; {
+        r, n := utf8.DecodeRuneInString(d.text[o:])
+        char += utf16Len(r)
+        o += n
+    }
+    return parse.Pos(o)
+}
+
+func utf16Len(r rune) int {
+    if r >= 0x10000 {
+        return 2
+    }
+    return 1
+}
+
+// textRange returns the range from pos to end.
+func (d *document) textRange(pos, end parse.Pos) textRange {
+    return textRange{d.position(pos), d.position(end)}
+}
+
+// origin returns the range of the 1-based line and byte column.
+func (d *document) origin(line, col int) textRange {
+    off := len(d.text)
+    if line >= 1 && line <= len(d.lines) {
+        off = d.lines[line-1] + col - 1
+    }
+    p := d.position(parse.Pos(off))
+    return textRange{p, p}
+}
+
+// analyze parses and resolves the document and returns its problems. A
+// reference config, if not nil, is the fallback that the document is
+// checked against.
+func (d *document) analyze(reference *parse.Config) []diagnostic {
+    diags := []diagnostic{}
+    t := parse.NewFile(d.path)
+    t.Mode = parse.RecoverErrors
+    t.Parse(d.text)
+    for _, e := range t.Errors {
+        if e.Name == d.path {
+            diags = append(diags, d.diagnostic(d.textRange(e.Pos, e.Pos), severityError, e.Msg))
+        } else {
+            diags = append(diags, d.diagnostic(d.includeRange(e.Name), severityError, e.Error()))
+        }
+    }
+    d.conf, d.resolved = t.GetConfig(), nil
+    if len(t.Errors) > 0 {
+        return diags
+    }
+    conf := d.conf
+    if reference != nil {
+        conf = conf.WithFallback(reference)
+    }
+    // Reading files and secret stores, or decrypting, as the user types
+    // would be slow and surprising.
+    resolved, err := conf.ResolveWith(&parse.ResolveOptions{Offline: true})
+    if err != nil {
+        return append(diags, d.diagnostic(d.errorRange(err.Error()), severityError, err.Error()))
+    }
+    d.resolved = resolved
+    if reference == nil {
+        return diags
+    }
+    if err, ok := resolved.CheckValid(reference).(parse.ValidationErrors); ok {
+        for _, e := range err {
+            if e.Origin.Name == d.path {
+                diags = append(diags, d.diagnostic(d.origin(e.Origin.Line, e.Origin.Col), severityWarning, e.Msg))
+            }
+        }
+    }
+    return diags
+}
+
+func (d *document) diagnostic(r textRange, severity int, msg string) diagnostic {
+    return diagnostic{Range: r, Severity: severity, Source: "hocon", Message: msg}
+}
+
+// includeRange returns the range of the include statement that brings in
+// the file name, or of the first include statement.
+func (d *document) includeRange(name string) textRange {
+    var found *parse.Reference
+    for _, r := range d.refs {
+        if r.Include == "" {
+            continue
+        }
+        if found == nil || d.includePath(r.Include) == name {
+            found = r
+        }
+    }
+    if found == nil {
+        return d.textRange(0, 0)
+    }
+    return d.textRange(found.Pos, found.End)
+}
+
+// errorRange returns the range of an error message that starts with a
+// position in the document, file:line:col, or the start of the document.
+func (d *document) errorRange(msg string) textRange {
+    var line, col int
+    if strings.HasPrefix(msg, d.path+":") {
+        fmt.Sscanf(msg[len(d.path)+1:], "%d:%d", &line, &col)
+    }
+    if line == 0 {
+        return d.textRange(0, 0)
+    }
+    return d.origin(line, col)
+}
+
+// includePath returns the path of a file named in an include statement.
+func (d *document) includePath(name string) string {
+    if filepath.IsAbs(name) {
+        return name
+    }
+    return filepath.Join(filepath.Dir(d.path), name)
+}
+
+// reference returns the substitution or include statement at off.
+func (d *document) reference(off parse.Pos) *parse.Reference {
+    for _, r := range d.refs {
+        if r.Pos <= off && off <= r.End {
+            return r
+        }
+    }
+    return nil
+}
+
+// symbolAt returns the field whose key is at off.
+func symbolAt(symbols []*parse.Symbol, off parse.Pos) *parse.Symbol {
+    for _, s := range symbols {
+        if s.Pos <= off && off <= s.KeyEnd {
+            return s
+        }
+        if s.Pos <= off && off <= s.End {
+            if found := symbolAt(s.Children, off); found != nil {
+                return found
+            }
+        }
+    }
+    return nil
+}
+
+// lastSymbol returns the last field defining path, which is the one that
+// takes effect.
+func lastSymbol(symbols []*parse.Symbol, path parse.Path) *parse.Symbol {
+    var found *parse.Symbol
+    for _, s := range symbols {
+        if s.Path.String() == path.String() {
+            found = s
+        }
+        if inner := lastSymbol(s.Children, path); inner != nil {
+            found = inner
+        }
+    }
+    return found
+}
+
+// enclosing returns the path of the innermost object in braces around off.
+func enclosing(symbols []*parse.Symbol, off parse.Pos) parse.Path {
+    for _, s := range symbols {
+        if s.KeyEnd < off && off < s.End {
+            if inner := enclosing(s.Children, off); inner != nil {
+                return inner
+            }
+            if s.Object {
+                return s.Path
+            }
+        }
+    }
+    return nil
+}
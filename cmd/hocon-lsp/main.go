@@ -0,0 +1,29 @@
+// Command hocon-lsp is a Language Server Protocol server for HOCON
+// configuration files. It speaks JSON-RPC over standard input and output
+// and provides:
+//
+//	diagnostics for parse and substitution errors, and for values whose
+//	type differs from the reference config
+//	go to definition for ${...} substitutions and include statements
+//	hover with the resolved value of a key or substitution and its origin
+//	document symbols for the keys of objects
+//	completion of keys and substitutions from the reference config
+//
+// Usage:
+//
+//	hocon-lsp [-reference reference.conf]
+//
+// The client may also name the reference config in the initialization
+// options: {"reference": "path/to/reference.conf"}.
+package main
+
+import (
+    "flag"
+    "os"
+)
+
+func main() {
+    reference := flag.String("reference", "", "reference config with the defaults of every setting")
+    flag.Parse()
+    os.Exit(newServer(os.Stdin, os.Stdout, os.Stderr, *reference).serve())
+}
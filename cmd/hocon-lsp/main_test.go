@@ -0,0 +1,248 @@
+package main
+
+import (
+    "bufio"
+    "encoding/json"
+    "io"
+    "io/ioutil"
+    "os"
+    "path/filepath"
+    "reflect"
+    "strings"
+    "testing"
+)
+
+// client is a scripted LSP client talking to a server in the same process.
+type client struct {
+    t     *testing.T
+    w     io.Writer
+    r     *bufio.Reader
+    id    int
+    notes []*message // notifications received while waiting for responses
+}
+
+func (c *client) notify(method string, params interface{}) {
+    if err := writeMessage(c.w, &notification{JSONRPC: "2.0", Method: method, Params: params}); err != nil {
+        c.t.Fatal(err)
+    }
+}
+
+// call sends a request and decodes its result into result. It returns the
+// error of the response, if any.
+func (c *client) call(method string, params, result interface{}) *responseError {
+    c.id++
+    id, _ := json.Marshal(c.id)
+    req := &message{JSONRPC: "2.0", ID: id, Method: method}
+    req.Params, _ = json.Marshal(params)
+    if err := writeMessage(c.w, req); err != nil {
+        c.t.Fatal(err)
+    }
+    for {
+        msg := c.read()
+        if msg.Method != "" {
+            c.notes = append(c.notes, msg)
+            continue
+        }
+        if string(msg.ID) != string(id) {
+            c.t.Fatalf("%s: response to %s, expected %s", method, msg.ID, id)
+        }
+        if msg.Error != nil {
+            return msg.Error
+        }
+        if err := json.Unmarshal(msg.Result, result); err != nil {
+            c.t.Fatalf("%s: %v", method, err)
+        }
+        return nil
+    }
+}
+
+func (c *client) read() *message {
+    msg, err := readMessage(c.r)
+    if err != nil {
+        c.t.Fatal(err)
+    }
+    return msg
+}
+
+// diagnostics returns the next diagnostics published.
+func (c *client) diagnostics() publishDiagnosticsParams {
+    msg := c.read()
+    var params publishDiagnosticsParams
+    if msg.Method != "textDocument/publishDiagnostics" {
+        c.t.Fatalf("got %s, expected diagnostics", msg.Method)
+    }
+    if err := json.Unmarshal(msg.Params, &params); err != nil {
+        c.t.Fatal(err)
+    }
+    return params
+}
+
+// at returns the position of the n-th byte after the first match of s in
+// text.
+func at(text, s string, n int) position {
+    off := strings.Index(text, s) + n
+    return position{strings.Count(text[:off], "\n"), off - strings.LastIndex(text[:off], "\n") - 1}
+}
+
+const appText = `include "base.conf"
+akka {
+  remote.port = 2553
+  loglevel = ${level}
+}
+level = DEBUG
+url = ${db.url}
+`
+
+func TestServer(t *testing.T) {
+    dir, err := ioutil.TempDir("", "hocon-lsp")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer os.RemoveAll(dir)
+    files := map[string]string{
+        "reference.conf": "akka { remote { port = 2552, host = localhost }, loglevel = INFO }\n",
+        "base.conf":      "db.url = \"jdbc:x\"\n",
+    }
+    for name, text := range files {
+        if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(text), 0644); err != nil {
+            t.Fatal(err)
+        }
+    }
+
+    toServer, fromClient := io.Pipe()
+    fromServer, toClient := io.Pipe()
+    done := make(chan int)
+    go func() {
+        done <- newServer(toServer, toClient, ioutil.Discard, "").serve()
+    }()
+    c := &client{t: t, w: fromClient, r: bufio.NewReader(fromServer)}
+
+    var init struct {
+        Capabilities struct {
+            HoverProvider bool
+        }
+    }
+    c.call("initialize", map[string]interface{}{
+        "initializationOptions": map[string]string{"reference": filepath.Join(dir, "reference.conf")},
+    }, &init)
+    if !init.Capabilities.HoverProvider {
+        t.Errorf("initialize: no hover: %+v", init)
+    }
+    c.notify("initialized", struct{}{})
+
+    uri := pathURI(filepath.Join(dir, "app.conf"))
+    baseURI := pathURI(filepath.Join(dir, "base.conf"))
+    doc := textDocumentIdentifier{uri}
+    c.notify("textDocument/didOpen", map[string]interface{}{
+        "textDocument": map[string]interface{}{"uri": uri, "languageId": "hocon", "version": 1, "text": appText},
+    })
+    if diags := c.diagnostics(); diags.URI != uri || len(diags.Diagnostics) != 0 {
+        t.Errorf("didOpen: unexpected diagnostics %+v", diags)
+    }
+
+    var h hover
+    c.call("textDocument/hover", textDocumentPositionParams{doc, at(appText, "${level}", 3)}, &h)
+    if !strings.Contains(h.Contents.Value, "level = DEBUG") || !strings.Contains(h.Contents.Value, "app.conf:6:9") {
+        t.Errorf("hover on substitution: %q", h.Contents.Value)
+    }
+    c.call("textDocument/hover", textDocumentPositionParams{doc, at(appText, "port", 1)}, &h)
+    if !strings.Contains(h.Contents.Value, "akka.remote.port = 2553") {
+        t.Errorf("hover on key: %q", h.Contents.Value)
+    }
+
+    definitions := []struct {
+        pos    position
+        expect location
+    }{
+        {at(appText, "${level}", 4), location{uri, textRange{position{5, 0}, position{5, 5}}}},
+        {at(appText, "${db.url}", 2), location{baseURI, textRange{position{0, 9}, position{0, 9}}}},
+        {at(appText, "base.conf", 0), location{URI: baseURI}},
+    }
+    for _, test := range definitions {
+        var loc location
+        c.call("textDocument/definition", textDocumentPositionParams{doc, test.pos}, &loc)
+        if loc != test.expect {
+            t.Errorf("definition at %v: got %+v, expected %+v", test.pos, loc, test.expect)
+        }
+    }
+
+    var symbols []documentSymbol
+    c.call("textDocument/documentSymbol", textDocumentParams{doc}, &symbols)
+    var names []string
+    var list func(symbols []documentSymbol, prefix string)
+    list = func(symbols []documentSymbol, prefix string) {
+        for _, s := range symbols {
+            names = append(names, prefix+s.Name)
+            list(s.Children, prefix+s.Name+"/")
+        }
+    }
+    list(symbols, "")
+    expect := []string{"akka", "akka/remote.port", "akka/loglevel", "level", "url"}
+    if !reflect.DeepEqual(names, expect) || symbols[0].Kind != symbolObject || symbols[0].Children[0].Kind != symbolNumber {
+        t.Errorf("symbols: got %v %+v, expected %v", names, symbols, expect)
+    }
+
+    // An edit that breaks the file, with completion in the broken part.
+    edited := "akka {\n  remote.\n}\nx = ${akka.lo"
+    c.notify("textDocument/didChange", map[string]interface{}{
+        "textDocument":   map[string]interface{}{"uri": uri, "version": 2},
+        "contentChanges": []map[string]string{{"text": edited}},
+    })
+    if diags := c.diagnostics(); len(diags.Diagnostics) == 0 || diags.Diagnostics[0].Severity != severityError {
+        t.Errorf("didChange: expected an error, got %+v", diags)
+    }
+    completions := []struct {
+        pos    position
+        expect []completionItem
+    }{
+        {at(edited, "remote.", 7), []completionItem{
+            {Label: "host", Kind: completionProperty, Detail: "default: localhost"},
+            {Label: "port", Kind: completionProperty, Detail: "default: 2552"},
+        }},
+        {at(edited, "  remote", 2), []completionItem{
+            {Label: "loglevel", Kind: completionProperty, Detail: "default: INFO"},
+            {Label: "remote", Kind: completionModule},
+        }},
+        {at(edited, "${akka.lo", 9), []completionItem{
+            {Label: "loglevel", Kind: completionProperty, Detail: "default: INFO"},
+        }},
+    }
+    for _, test := range completions {
+        var items []completionItem
+        c.call("textDocument/completion", textDocumentPositionParams{doc, test.pos}, &items)
+        if !reflect.DeepEqual(items, test.expect) {
+            t.Errorf("completion at %v: got %+v, expected %+v", test.pos, items, test.expect)
+        }
+    }
+
+    // A value of the wrong type for the reference config.
+    c.notify("textDocument/didChange", map[string]interface{}{
+        "textDocument":   map[string]interface{}{"uri": uri, "version": 3},
+        "contentChanges": []map[string]string{{"text": "akka.remote.port = yes\n"}},
+    })
+    diags := c.diagnostics()
+    if len(diags.Diagnostics) != 1 || diags.Diagnostics[0].Severity != severityWarning ||
+        !strings.Contains(diags.Diagnostics[0].Message, "expected number") ||
+        diags.Diagnostics[0].Range.Start != (position{0, 19}) {
+        t.Errorf("type check: got %+v", diags)
+    }
+
+    // A body that is not JSON gets an error, and the server carries on.
+    if _, err := io.WriteString(c.w, "Content-Length: 5\r\n\r\n{oops"); err != nil {
+        t.Fatal(err)
+    }
+    if msg := c.read(); msg.Error == nil || msg.Error.Code != codeParseError || string(msg.ID) != "null" {
+        t.Errorf("bad JSON: got %+v", msg)
+    }
+    if err := c.call("frob", nil, nil); err == nil || err.Code != codeMethodNotFound {
+        t.Errorf("unknown method: got %v", err)
+    }
+    var result interface{}
+    if err := c.call("shutdown", nil, &result); err != nil || result != nil {
+        t.Errorf("shutdown: %v, %v", result, err)
+    }
+    c.notify("exit", nil)
+    if status := <-done; status != 0 {
+        t.Errorf("exit status %d", status)
+    }
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+    "bufio"
+    "encoding/json"
+    "fmt"
+    "io"
+    "net/textproto"
+    "strconv"
+)
+
+// JSON-RPC error codes.
+const (
+    codeParseError     = -32700
+    codeInvalidRequest = -32600
+    codeMethodNotFound = -32601
+    codeInvalidParams  = -32602
+)
+
+// message is a JSON-RPC request, notification or response. Requests and
+// responses have an id, notifications do not.
+type message struct {
+    JSONRPC string          `json:"jsonrpc"`
+    ID      json.RawMessage `json:"id,omitempty"`
+    Method  string          `json:"method,omitempty"`
+    Params  json.RawMessage `json:"params,omitempty"`
+    Result  json.RawMessage `json:"result,omitempty"`
+    Error   *responseError  `json:"error,omitempty"`
+}
+
+type response struct {
+    JSONRPC string          `json:"jsonrpc"`
+    ID      json.RawMessage `json:"id"`
+    Result  interface{}     `json:"result"`
+}
+
+type errorResponse struct {
+    JSONRPC string          `json:"jsonrpc"`
+    ID      json.RawMessage `json:"id"`
+    Error   *responseError  `json:"error"`
+}
+
+type responseError struct {
+    Code    int    `json:"code"`
+    Message string `json:"message"`
+}
+
+func (e *responseError) Error() string {
+    return fmt.Sprintf("%s (%d)", e.Message, e.Code)
+}
+
+type notification struct {
+    JSONRPC string      `json:"jsonrpc"`
+    Method  string      `json:"method"`
+    Params  interface{} `json:"params"`
+}
+
+// readMessage reads a message framed by a Content-Length header. If the
+// body is not a valid message it returns a *responseError, after which the
+// next message can still be read.
+func readMessage(r *bufio.Reader) (*message, error) {
+    header, err := textproto.NewReader(r).ReadMIMEHeader()
+    if err != nil {
+        return nil, err
+    }
+    n, err := strconv.Atoi(header.Get("Content-Length"))
+    if err != nil || n < 0 {
+        return nil, fmt.Errorf("bad Content-Length %q", header.Get("Content-Length"))
+    }
+    body := make([]byte, n)
+    if _, err := io.ReadFull(r, body); err != nil {
+        return nil, err
+    }
+    msg := new(message)
+    if err := json.Unmarshal(body, msg); err != nil {
+        return nil, &responseError{Code: codeParseError, Message: err.Error()}
+    }
+    return msg, nil
+}
+
+// writeMessage writes v as a message framed by a Content-Length header.
+func writeMessage(w io.Writer, v interface{}) error {
+    body, err := json.Marshal(v)
+    if err != nil {
+        return err
+    }
+    if _, err := fmt.Fprintf(w, "Content-Length: %d\r\n\r\n", len(body)); err != nil {
+        return err
+    }
+    _, err = w.Write(body)
+    return err
+}
+
+// The parts of the Language Server Protocol the server uses.
+
+type position struct {
+    Line      int `json:"line"`
+    Character int `json:"character"`
+}
+
+type textRange struct {
+    Start position `json:"start"`
+    End   position `json:"end"`
+}
+
+type location struct {
+    URI   string    `json:"uri"`
+    Range textRange `json:"range"`
+}
+
+type textDocumentIdentifier struct {
+    URI string `json:"uri"`
+}
+
+type textDocumentPositionParams struct {
+    TextDocument textDocumentIdentifier `json:"textDocument"`
+    Position     position               `json:"position"`
+}
+
+type initializeParams struct {
+    InitializationOptions struct {
+        Reference string `json:"reference"`
+    } `json:"initializationOptions"`
+}
+
+type didOpenParams struct {
+    TextDocument struct {
+        URI  string `json:"uri"`
+        Text string `json:"text"`
+    } `json:"textDocument"`
+}
+
+type didChangeParams struct {
+    TextDocument   textDocumentIdentifier `json:"textDocument"`
+    ContentChanges []struct {
+        Text string `json:"text"`
+    } `json:"contentChanges"`
+}
+
+type textDocumentParams struct {
+    TextDocument textDocumentIdentifier `json:"textDocument"`
+}
+
+// Diagnostic severities.
+const (
+    severityError   = 1
+    severityWarning = 2
+)
+
+type diagnostic struct {
+    Range    textRange `json:"range"`
+    Severity int       `json:"severity"`
+    Source   string    `json:"source"`
+    Message  string    `json:"message"`
+}
+
+type publishDiagnosticsParams struct {
+    URI         string       `json:"uri"`
+    Diagnostics []diagnostic `json:"diagnostics"`
+}
+
+type markupContent struct {
+    Kind  string `json:"kind"`
+    Value string `json:"value"`
+}
+
+type hover struct {
+    Contents markupContent `json:"contents"`
+    Range    textRange     `json:"range"`
+}
+
+// Symbol kinds.
+const (
+    symbolProperty = 7
+    symbolString   = 15
+    symbolNumber   = 16
+    symbolBoolean  = 17
+    symbolArray    = 18
+    symbolObject   = 19
+    symbolNull     = 21
+)
+
+type documentSymbol struct {
+    Name           string           `json:"name"`
+    Kind           int              `json:"kind"`
+    Range          textRange        `json:"range"`
+    SelectionRange textRange        `json:"selectionRange"`
+    Children       []documentSymbol `json:"children,omitempty"`
+}
+
+// Completion item kinds.
+const (
+    completionModule   = 9
+    completionProperty = 10
+)
+
+type completionItem struct {
+    Label  string `json:"label"`
+    Kind   int    `json:"kind"`
+    Detail string `json:"detail,omitempty"`
+}
@@ -0,0 +1,391 @@
+package main
+
+import (
+    "bufio"
+    "bytes"
+    "encoding/json"
+    "fmt"
+    "io"
+    "io/ioutil"
+    "sort"
+    "strings"
+
+    "github.com/liyinhgqw/typesafe-config/parse"
+)
+
+// server is a language server for one client.
+type server struct {
+    in        *bufio.Reader
+    out       io.Writer
+    log       io.Writer
+    reference string        // the file of the reference config
+    ref       *parse.Config // the reference config, or nil
+    docs      map[string]*document
+    shutdown  bool // a shutdown request has been received
+}
+
+func newServer(in io.Reader, out, log io.Writer, reference string) *server {
+    return &server{in: bufio.NewReader(in), out: out, log: log, reference: reference, docs: make(map[string]*document)}
+}
+
+// serve handles messages until the client sends exit or closes the
+// connection. It returns the exit status: 0 if the client asked the server
+// to shut down first, 1 otherwise.
+func (s *server) serve() int {
+    for {
+        msg, err := readMessage(s.in)
+        if rerr, ok := err.(*responseError); ok {
+            // The id of a message that cannot be read is unknown.
+            s.send(&errorResponse{JSONRPC: "2.0", Error: rerr})
+            continue
+        }
+        if err != nil {
+            if err != io.EOF {
+                fmt.Fprintf(s.log, "hocon-lsp: %v\n", err)
+            }
+            return 1
+        }
+        if msg.Method == "exit" {
+            if s.shutdown {
+                return 0
+            }
+            return 1
+        }
+        s.handle(msg)
+    }
+}
+
+// handle answers a request or acts on a notification.
+func (s *server) handle(msg *message) {
+    result, err := s.dispatch(msg)
+    if len(msg.ID) == 0 {
+        if err != nil {
+            fmt.Fprintf(s.log, "hocon-lsp: %s: %v\n", msg.Method, err)
+        }
+        return
+    }
+    if err != nil {
+        rerr, ok := err.(*responseError)
+        if !ok {
+            rerr = &responseError{Code: codeInvalidParams, Message: err.Error()}
+        }
+        s.send(&errorResponse{JSONRPC: "2.0", ID: msg.ID, Error: rerr})
+        return
+    }
+    s.send(&response{JSONRPC: "2.0", ID: msg.ID, Result: result})
+}
+
+func (s *server) send(v interface{}) {
+    if err := writeMessage(s.out, v); err != nil {
+        fmt.Fprintf(s.log, "hocon-lsp: %v\n", err)
+    }
+}
+
+func (s *server) dispatch(msg *message) (interface{}, error) {
+    if s.shutdown && len(msg.ID) > 0 {
+        return nil, &responseError{Code: codeInvalidRequest, Message: "the server is shut down"}
+    }
+    switch msg.Method {
+        case "initialize":
+            var params initializeParams
+            if err := unmarshal(msg.Params, &params); err != nil {
+                return nil, err
+            }
+            return s.initialize(&params), nil
+        case "shutdown":
+            s.shutdown = true
+            return nil, nil
+        case "textDocument/didOpen":
+            var params didOpenParams
+            if err := unmarshal(msg.Params, &params); err != nil {
+                return nil, err
+            }
+            s.update(params.TextDocument.URI, params.TextDocument.Text)
+            return nil, nil
+        case "textDocument/didChange":
+            var params didChangeParams
+            if err := unmarshal(msg.Params, &params); err != nil {
+                return nil, err
+            }
+            // Changes are whole documents; the last one holds.
+            if n := len(params.ContentChanges); n > 0 {
+                s.update(params.TextDocument.URI, params.ContentChanges[n-1].Text)
+            }
+            return nil, nil
+        case "textDocument/didClose":
+            var params textDocumentParams
+            if err := unmarshal(msg.Params, &params); err != nil {
+                return nil, err
+            }
+            delete(s.docs, params.TextDocument.URI)
+            s.send(&notification{JSONRPC: "2.0", Method: "textDocument/publishDiagnostics",
+                Params: &publishDiagnosticsParams{URI: params.TextDocument.URI, Diagnostics: []diagnostic{}}})
+            return nil, nil
+        case "textDocument/definition", "textDocument/hover", "textDocument/completion":
+            var params textDocumentPositionParams
+            if err := unmarshal(msg.Params, &params); err != nil {
+                return nil, err
+            }
+            d := s.docs[params.TextDocument.URI]
+            if d == nil {
+                return nil, nil
+            }
+            off := d.offset(params.Position)
+            switch msg.Method {
+                case "textDocument/definition":
+                    return s.definition(d, off), nil
+                case "textDocument/hover":
+                    return s.hover(d, off), nil
+            }
+            return s.completion(d, off), nil
+        case "textDocument/documentSymbol":
+            var params textDocumentParams
+            if err := unmarshal(msg.Params, &params); err != nil {
+                return nil, err
+            }
+            d := s.docs[params.TextDocument.URI]
+            if d == nil {
+                return []documentSymbol{}, nil
+            }
+            return d.documentSymbols(d.symbols), nil
+    }
+    if strings.HasPrefix(msg.Method, "$/") || len(msg.ID) == 0 {
+        // Notifications, such as initialized, need no answer.
+        return nil, nil
+    }
+    return nil, &responseError{Code: codeMethodNotFound, Message: "method not found: " + msg.Method}
+}
+
+func unmarshal(params json.RawMessage, v interface{}) error {
+    if len(params) == 0 {
+        return nil
+    }
+    return json.Unmarshal(params, v)
+}
+
+func (s *server) initialize(params *initializeParams) interface{} {
+    if params.InitializationOptions.Reference != "" {
+        s.reference = params.InitializationOptions.Reference
+    }
+    if s.reference != "" {
+        tree, err := parse.ParseFile(s.reference)
+        if err != nil {
+            fmt.Fprintf(s.log, "hocon-lsp: reference: %v\n", err)
+        } else {
+            s.ref = tree.GetConfig()
+            if resolved, err := s.ref.ResolveWith(&parse.ResolveOptions{Offline: true}); err == nil {
+                s.ref = resolved
+            }
+        }
+    }
+    return map[string]interface{}{
+        "capabilities": map[string]interface{}{
+            "textDocumentSync":       1, // the whole document on every change
+            "definitionProvider":     true,
+            "hoverProvider":          true,
+            "documentSymbolProvider": true,
+            "completionProvider": map[string]interface{}{
+                "triggerCharacters": []string{".", "{"},
+            },
+        },
+        "serverInfo": map[string]string{"name": "hocon-lsp"},
+    }
+}
+
+// update sets the text of a document and publishes its diagnostics.
+func (s *server) update(uri, text string) {
+    d := newDocument(uri, text)
+    s.docs[uri] = d
+    s.send(&notification{JSONRPC: "2.0", Method: "textDocument/publishDiagnostics",
+        Params: &publishDiagnosticsParams{URI: uri, Diagnostics: d.analyze(s.ref)}})
+}
+
+// definition returns where the substitution at off is defined, or the
+// file named by the include statement at off.
+func (s *server) definition(d *document, off parse.Pos) interface{} {
+    r := d.reference(off)
+    if r == nil {
+        return nil
+    }
+    if r.Include != "" {
+        return &location{URI: pathURI(d.includePath(r.Include))}
+    }
+    if sym := lastSymbol(d.symbols, r.Path); sym != nil {
+        return &location{URI: d.uri, Range: d.textRange(sym.Pos, sym.KeyEnd)}
+    }
+    // The value may come from an included file or the reference config.
+    for _, conf := range []*parse.Config{d.conf, d.resolved} {
+        if conf == nil {
+            continue
+        }
+        value, err := conf.GetValue(r.Path.String())
+        if err != nil {
+            continue
+        }
+        o := value.Origin()
+        if o.Name == "" || o.Line == 0 {
+            continue
+        }
+        if other := s.file(o.Name); other != nil {
+            return &location{URI: other.uri, Range: other.origin(o.Line, o.Col)}
+        }
+    }
+    return nil
+}
+
+// file returns the document of the named file, open or read from disk.
+func (s *server) file(name string) *document {
+    uri := pathURI(name)
+    if d, ok := s.docs[uri]; ok {
+        return d
+    }
+    text, err := ioutil.ReadFile(name)
+    if err != nil {
+        return nil
+    }
+    return newDocument(uri, string(text))
+}
+
+// hover shows the value, resolved if possible, of the key or substitution
+// at off and where it comes from.
+func (s *server) hover(d *document, off parse.Pos) interface{} {
+    var path parse.Path
+    var pos, end parse.Pos
+    if r := d.reference(off); r != nil && r.Include == "" {
+        path, pos, end = r.Path, r.Pos, r.End
+    } else if sym := symbolAt(d.symbols, off); sym != nil && off <= sym.KeyEnd {
+        path, pos, end = sym.Path, sym.Pos, sym.KeyEnd
+    } else {
+        return nil
+    }
+    conf := d.conf
+    if d.resolved != nil {
+        conf = d.resolved
+    }
+    value, err := conf.GetValue(path.String())
+    if err != nil {
+        return nil
+    }
+    var b bytes.Buffer
+    b.WriteString("```hocon\n")
+    switch value.Root().Type() {
+        case parse.NodeMap, parse.NodeList:
+            out, _ := value.Render(parse.FormatHOCON)
+            if value.Root().Type() == parse.NodeMap {
+                fmt.Fprintf(&b, "%s {\n%s}\n", path, indent(string(out)))
+            } else {
+                fmt.Fprintf(&b, "%s = %s", path, out)
+            }
+        default:
+            fmt.Fprintf(&b, "%s = %s\n", path, value)
+    }
+    b.WriteString("```\n")
+    if o := value.Origin(); o.Name != "" {
+        fmt.Fprintf(&b, "\nfrom %s\n", o)
+    }
+    return &hover{Contents: markupContent{Kind: "markdown", Value: b.String()}, Range: d.textRange(pos, end)}
+}
+
+func indent(s string) string {
+    lines := strings.SplitAfter(s, "\n")
+    for i, l := range lines {
+        if l != "" && l != "\n" {
+            lines[i] = "  " + l
+        }
+    }
+    return strings.Join(lines, "")
+}
+
+// documentSymbols returns symbols as LSP document symbols.
+func (d *document) documentSymbols(symbols []*parse.Symbol) []documentSymbol {
+    result := []documentSymbol{}
+    for _, sym := range symbols {
+        result = append(result, documentSymbol{
+            Name:           sym.Key,
+            Kind:           d.symbolKind(sym),
+            Range:          d.textRange(sym.Pos, sym.End),
+            SelectionRange: d.textRange(sym.Pos, sym.KeyEnd),
+            Children:       d.documentSymbols(sym.Children),
+        })
+    }
+    return result
+}
+
+func (d *document) symbolKind(sym *parse.Symbol) int {
+    if sym.Object {
+        return symbolObject
+    }
+    if d.conf == nil {
+        return symbolProperty
+    }
+    value, err := d.conf.GetValue(sym.Path.String())
+    if err != nil {
+        return symbolProperty
+    }
+    switch value.Root().Type() {
+        case parse.NodeList:
+            return symbolArray
+        case parse.NodeMap:
+            return symbolObject
+        case parse.NodeString, parse.NodeText:
+            return symbolString
+        case parse.NodeNumber:
+            return symbolNumber
+        case parse.NodeBool:
+            return symbolBoolean
+        case parse.NodeNil:
+            return symbolNull
+    }
+    return symbolProperty
+}
+
+// completion returns the keys that may be written at off: those of the
+// reference config, and of the document itself, in the object around off
+// and under the path already typed. Inside ${ it completes the path of
+// the substitution.
+func (s *server) completion(d *document, off parse.Pos) interface{} {
+    lineStart := d.lines[d.position(off).Line]
+    before := d.text[lineStart:off]
+    var base, typed string
+    if i := strings.LastIndex(before, "${"); i >= 0 && !strings.Contains(before[i:], "}") {
+        typed = strings.TrimPrefix(before[i+2:], "?")
+    } else {
+        typed = before[strings.LastIndexAny(before, " \t{,")+1:]
+        base = enclosing(d.symbols, off).String()
+    }
+    partial := typed
+    if i := strings.LastIndex(typed, "."); i >= 0 {
+        if base != "" {
+            base += "."
+        }
+        base += typed[:i]
+        partial = typed[i+1:]
+    }
+    items := []completionItem{}
+    seen := make(map[string]bool)
+    for _, conf := range []*parse.Config{s.ref, d.conf} {
+        if conf == nil {
+            continue
+        }
+        obj, err := conf.GetValue(base)
+        if err != nil || obj.Root().Type() != parse.NodeMap {
+            continue
+        }
+        for _, k := range obj.Keys() {
+            if seen[k] || !strings.HasPrefix(k, partial) {
+                continue
+            }
+            seen[k] = true
+            value, _ := obj.Get(k)
+            item := completionItem{Label: parse.Path{k}.String(), Kind: completionProperty}
+            if value.Root().Type() == parse.NodeMap {
+                item.Kind = completionModule
+            } else if conf == s.ref {
+                item.Detail = "default: " + value.String()
+            }
+            items = append(items, item)
+        }
+    }
+    sort.Slice(items, func(i, j int) bool { return items[i].Label < items[j].Label })
+    return items
+}
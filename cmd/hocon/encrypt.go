@@ -0,0 +1,69 @@
+package main
+
+import (
+    "encoding/base64"
+    "fmt"
+    "io/ioutil"
+    "os"
+
+    "github.com/liyinhgqw/typesafe-config/parse"
+)
+
+// encrypt encrypts the value at a path of a file in place, leaving the
+// rest of the file as it is.
+func (cmd *command) encrypt(args []string) int {
+    fs := cmd.flags("encrypt")
+    keyfile := fs.String("keyfile", "", "keyring file, instead of the one of the environment")
+    if !cmd.parseArgs(fs, args, 2, "[--keyfile keys] file path") {
+        return exitUsage
+    }
+    var keys *parse.Keyring
+    var err error
+    if *keyfile != "" {
+        keys, err = parse.LoadKeyring(*keyfile)
+    } else {
+        keys, err = parse.KeyringFromEnv()
+        if err == nil && keys == nil {
+            err = fmt.Errorf("no keys: use --keyfile or set %s or %s", parse.KeyfileEnv, parse.KeysEnv)
+        }
+    }
+    if err != nil {
+        return cmd.fail(err, exitUsage)
+    }
+    name := fs.Arg(0)
+    info, err := os.Stat(name)
+    if err != nil {
+        return cmd.fail(err, exitUsage)
+    }
+    src, err := ioutil.ReadFile(name)
+    if err != nil {
+        return cmd.fail(err, exitUsage)
+    }
+    out, err := parse.EncryptSource(name, src, fs.Arg(1), keys)
+    if err != nil {
+        return cmd.fail(err, exitFailure)
+    }
+    if err := replaceFile(name, out, info.Mode().Perm()); err != nil {
+        return cmd.fail(err, exitFailure)
+    }
+    return exitOK
+}
+
+// keygen prints a new random key as a line of a keyring file.
+func (cmd *command) keygen(args []string) int {
+    fs := cmd.flags("keygen")
+    name := fs.String("name", "default", "name of the key")
+    if !cmd.parseArgs(fs, args, 0, "[--name name]") {
+        return exitUsage
+    }
+    key, err := parse.GenerateKey()
+    if err != nil {
+        return cmd.fail(err, exitFailure)
+    }
+    // Check the name as a keyring would.
+    if err := parse.NewKeyring().Add(*name, key); err != nil {
+        return cmd.fail(err, exitUsage)
+    }
+    fmt.Fprintf(cmd.stdout, "%s:%s\n", *name, base64.StdEncoding.EncodeToString(key))
+    return exitOK
+}
@@ -0,0 +1,292 @@
+package main
+
+import (
+    "bytes"
+    "errors"
+    "fmt"
+    "io/ioutil"
+    "os"
+    "strings"
+
+    "github.com/liyinhgqw/typesafe-config/parse"
+)
+
+// format formats the named files, or the standard input, like gofmt: by
+// default the result is printed, -l lists the files that are not
+// formatted, -d prints diffs and -w rewrites the files. With -l or -d the
+// exit status is 1 if any file is not formatted, for use in CI.
+func (cmd *command) format(args []string) int {
+    fs := cmd.flags("fmt")
+    list := fs.Bool("l", false, "list files whose formatting differs")
+    diff := fs.Bool("d", false, "print diffs instead of the formatted files")
+    write := fs.Bool("w", false, "write the result to the files instead of printing it")
+    opts := new(parse.FormatOptions)
+    fs.BoolVar(&opts.SortKeys, "sort", false, "sort the fields of objects by key")
+    fs.BoolVar(&opts.Colon, "colon", false, `separate keys and values with ":"`)
+    fs.StringVar(&opts.Indent, "indent", "  ", "indent of each level")
+    if err := fs.Parse(args); err != nil {
+        return exitUsage
+    }
+    if fs.NArg() == 0 {
+        if *write {
+            return cmd.fail(errors.New("fmt: cannot use -w with standard input"), exitUsage)
+        }
+        src, err := ioutil.ReadAll(cmd.stdin)
+        if err != nil {
+            return cmd.fail(err, exitUsage)
+        }
+        return cmd.formatFile("<standard input>", src, opts, *list, *diff, false)
+    }
+    status := exitOK
+    for _, name := range fs.Args() {
+        src, err := ioutil.ReadFile(name)
+        var s int
+        if err != nil {
+            s = cmd.fail(err, exitUsage)
+        } else {
+            s = cmd.formatFile(name, src, opts, *list, *diff, *write)
+        }
+        if s > status {
+            status = s
+        }
+    }
+    return status
+}
+
+// formatFile formats src, read from the file name, and reports the
+// result.
+func (cmd *command) formatFile(name string, src []byte, opts *parse.FormatOptions, list, diff, write bool) int {
+    out, err := parse.FormatSource(name, src, opts)
+    if err != nil {
+        return cmd.fail(err, exitFailure)
+    }
+    changed := !bytes.Equal(src, out)
+    if !list && !diff && !write {
+        cmd.stdout.Write(out)
+        return exitOK
+    }
+    if list && changed {
+        fmt.Fprintln(cmd.stdout, name)
+    }
+    if diff && changed {
+        fmt.Fprint(cmd.stdout, unifiedDiff(name, src, out))
+    }
+    if write && changed {
+        info, err := os.Stat(name)
+        if err != nil {
+            return cmd.fail(err, exitUsage)
+        }
+        if err := replaceFile(name, out, info.Mode().Perm()); err != nil {
+            return cmd.fail(err, exitUsage)
+        }
+    }
+    if changed && (list || diff) {
+        return exitFailure
+    }
+    return exitOK
+}
+
+// edit is a line of a diff: kept (' '), removed ('-') or added ('+').
+type edit struct {
+    kind byte
+    text string
+}
+
+// diffContext is the number of unchanged lines shown around changes.
+const diffContext = 3
+
+// unifiedDiff returns the changes from a to b, the old and new text of
+// the file name, as a unified diff.
+func unifiedDiff(name string, a, b []byte) string {
+    edits := lineDiff(lines(a), lines(b))
+    var buf bytes.Buffer
+    fmt.Fprintf(&buf, "--- %s.orig\n+++ %s\n", name, name)
+    for start := 0; start < len(edits); {
+        c := start
+        for c < len(edits) && edits[c].kind == ' ' {
+            c++
+        }
+        if c == len(edits) {
+            break
+        }
+        lo := c - diffContext
+        if lo < start {
+            lo = start
+        }
+        // Changes closer than twice the context share a hunk.
+        hi := c
+        for {
+            for hi < len(edits) && edits[hi].kind != ' ' {
+                hi++
+            }
+            next := hi
+            for next < len(edits) && edits[next].kind == ' ' {
+                next++
+            }
+            if next == len(edits) || next-hi > 2*diffContext {
+                break
+            }
+            hi = next
+        }
+        end := hi + diffContext
+        if end > len(edits) {
+            end = len(edits)
+        }
+        aStart, bStart := count(edits[:lo])
+        aLen, bLen := count(edits[lo:end])
+        fmt.Fprintf(&buf, "@@ -%s +%s @@\n", span(aStart, aLen), span(bStart, bLen))
+        for _, e := range edits[lo:end] {
+            buf.WriteByte(e.kind)
+            buf.WriteString(e.text)
+            buf.WriteByte('\n')
+        }
+        start = end
+    }
+    return buf.String()
+}
+
+// lines splits text into lines without their newlines.
+func lines(text []byte) []string {
+    s := strings.TrimSuffix(string(text), "\n")
+    if s == "" {
+        return nil
+    }
+    return strings.Split(s, "\n")
+}
+
+// lineDiff returns the edits turning a into b, keeping their common
+// prefix and suffix and diffing the lines in between with myers.
+func lineDiff(a, b []string) []edit {
+    pre := 0
+    for pre < len(a) && pre < len(b) && a[pre] == b[pre] {
+        pre++
+    }
+    suf := 0
+    for suf < len(a)-pre && suf < len(b)-pre && a[len(a)-1-suf] == b[len(b)-1-suf] {
+        suf++
+    }
+    var edits []edit
+    for _, line := range a[:pre] {
+        edits = append(edits, edit{' ', line})
+    }
+    edits = append(edits, myers(a[pre:len(a)-suf], b[pre:len(b)-suf])...)
+    for _, line := range a[len(a)-suf:] {
+        edits = append(edits, edit{' ', line})
+    }
+    return edits
+}
+
+// maxTrace bounds the memory myers uses, in ints, before it gives up on
+// texts too different to be worth diffing line by line.
+const maxTrace = 1 << 22
+
+// myers returns a shortest list of edits turning a into b, by the
+// algorithm of Myers, "An O(ND) Difference Algorithm and Its Variations",
+// in time O((n+m)·d) and space O(d²) for d edits. If that takes more than
+// maxTrace, it replaces all of a with b.
+func myers(a, b []string) []edit {
+    n, m := len(a), len(b)
+    // v[off+k] is the furthest x reached on diagonal k = x-y; trace[d] is
+    // v for diagonals -d..d before step d.
+    off := n + m + 1
+    v := make([]int, 2*off+1)
+    var trace [][]int
+    size := 0
+    for d := 0; d <= n+m; d++ {
+        if size += 2*d + 1; size > maxTrace {
+            return replace(a, b)
+        }
+        trace = append(trace, append([]int(nil), v[off-d:off+d+1]...))
+        done := false
+        for k := -d; k <= d; k += 2 {
+            var x int
+            if k == -d || k != d && v[off+k-1] < v[off+k+1] {
+                x = v[off+k+1]
+            } else {
+                x = v[off+k-1] + 1
+            }
+            y := x - k
+            for x < n && y < m && a[x] == b[y] {
+                x++
+                y++
+            }
+            v[off+k] = x
+            if x >= n && y >= m {
+                done = true
+                break
+            }
+        }
+        if done {
+            break
+        }
+    }
+
+    // Walk back from the end to find the edits, last first.
+    var edits []edit
+    x, y := n, m
+    for d := len(trace) - 1; d >= 0; d-- {
+        px, py := 0, 0
+        if d > 0 {
+            prev := trace[d] // v[off+k] is prev[k+d]
+            k := x - y
+            pk := k - 1
+            if k == -d || k != d && prev[k-1+d] < prev[k+1+d] {
+                pk = k + 1
+            }
+            px = prev[pk+d]
+            py = px - pk
+        }
+        for x > px && y > py {
+            x--
+            y--
+            edits = append(edits, edit{' ', a[x]})
+        }
+        if d > 0 {
+            if x == px {
+                y--
+                edits = append(edits, edit{'+', b[y]})
+            } else {
+                x--
+                edits = append(edits, edit{'-', a[x]})
+            }
+        }
+    }
+    for i, j := 0, len(edits)-1; i < j; i, j = i+1, j-1 {
+        edits[i], edits[j] = edits[j], edits[i]
+    }
+    return edits
+}
+
+// replace returns the edits removing all of a and adding all of b.
+func replace(a, b []string) []edit {
+    edits := make([]edit, 0, len(a)+len(b))
+    for _, line := range a {
+        edits = append(edits, edit{'-', line})
+    }
+    for _, line := range b {
+        edits = append(edits, edit{'+', line})
+    }
+    return edits
+}
+
+// count returns the number of lines of the old and the new text in edits.
+func count(edits []edit) (a, b int) {
+    for _, e := range edits {
+        if e.kind != '+' {
+            a++
+        }
+        if e.kind != '-' {
+            b++
+        }
+    }
+    return
+}
+
+// span returns the range of n lines after the first start lines in the
+// form of a hunk header.
+func span(start, n int) string {
+    if n == 0 {
+        return fmt.Sprintf("%d,0", start)
+    }
+    return fmt.Sprintf("%d,%d", start+1, n)
+}
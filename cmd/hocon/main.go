@@ -0,0 +1,412 @@
+// Command hocon reads HOCON configuration files.
+//
+// Usage:
+//
+//	hocon get [--show-secrets] file path
+//	hocon render [--json] [--resolve] [--show-secrets] file
+//	hocon convert [--to hocon|json|yaml] [--show-secrets] file
+//	hocon validate [--reference ref.conf] [--schema schema.conf] [--json-schema schema.json] file
+//	hocon jsonschema ref.conf
+//	hocon diff [--show-secrets] a.conf b.conf
+//	hocon fmt [-l] [-d] [-w] [--sort] [--colon] [file ...]
+//	hocon encrypt [--keyfile keys] file path
+//	hocon keygen [--name name]
+//
+// A file named - is read from standard input; fmt reads it when given no
+// files. Include statements are followed, except by fmt. Encrypted values
+// are decrypted with the keyring of the CONFIG_KEYS or CONFIG_KEYFILE
+// environment variable, which encrypt uses too. Get, render, convert and
+// diff print the values of keys that look secret, such as passwords and
+// tokens, as <redacted> unless given --show-secrets. The exit status
+// is 0 on success, 1 if the input is invalid, the path is missing, the
+// configs differ or, with fmt -l or -d, a file is not formatted, and 2 on
+// usage errors and files that cannot be read.
+package main
+
+import (
+    "bytes"
+    "flag"
+    "fmt"
+    "io"
+    "io/ioutil"
+    "os"
+    "path/filepath"
+
+    "github.com/liyinhgqw/typesafe-config/parse"
+)
+
+// Exit statuses.
+const (
+    exitOK      = 0
+    exitFailure = 1
+    exitUsage   = 2
+)
+
+const usage = `usage: hocon <command> [flags] [args]
+
+commands:
+  get [--show-secrets] file path        print the value at path
+  render [--json] [--resolve] [--show-secrets] file
+                                        print the config as HOCON or JSON
+  convert [--to hocon|json|yaml] [--show-secrets] file
+                                        print the resolved config in a format
+  validate [--reference ref.conf] [--schema schema.conf] [--json-schema schema.json] file
+                                        check that the config is valid
+  jsonschema ref.conf                   print a JSON Schema of a reference config
+  diff [--show-secrets] a.conf b.conf   list the paths whose values differ
+  fmt [-l] [-d] [-w] [file ...]         format files, or list or diff unformatted ones
+  encrypt [--keyfile keys] file path    encrypt the value at path in the file
+  keygen [--name name]                  print a new key for a keyring file
+`
+
+func main() {
+    os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// command is the environment a command runs in.
+type command struct {
+    stdin          io.Reader
+    stdout, stderr io.Writer
+    redact         bool // the command redacts secrets, unless showSecrets
+    showSecrets    bool // --show-secrets
+}
+
+// run runs the command line args and returns the exit status.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+    if len(args) == 0 {
+        fmt.Fprint(stderr, usage)
+        return exitUsage
+    }
+    cmd := &command{stdin: stdin, stdout: stdout, stderr: stderr}
+    switch args[0] {
+        case "get":
+            return cmd.get(args[1:])
+        case "render":
+            return cmd.render(args[1:])
+        case "convert":
+            return cmd.convert(args[1:])
+        case "validate":
+            return cmd.validate(args[1:])
+        case "jsonschema":
+            return cmd.jsonSchema(args[1:])
+        case "diff":
+            return cmd.diff(args[1:])
+        case "fmt":
+            return cmd.format(args[1:])
+        case "encrypt":
+            return cmd.encrypt(args[1:])
+        case "keygen":
+            return cmd.keygen(args[1:])
+        case "help", "-h", "-help", "--help":
+            fmt.Fprint(stdout, usage)
+            return exitOK
+    }
+    fmt.Fprintf(stderr, "hocon: unknown command %q\n%s", args[0], usage)
+    return exitUsage
+}
+
+// flags returns a flag set for the named command.
+func (cmd *command) flags(name string) *flag.FlagSet {
+    fs := flag.NewFlagSet("hocon "+name, flag.ContinueOnError)
+    fs.SetOutput(cmd.stderr)
+    return fs
+}
+
+// parseArgs parses the flags of fs and checks the number of arguments
+// left. It returns false after reporting a usage error.
+func (cmd *command) parseArgs(fs *flag.FlagSet, args []string, n int, names string) bool {
+    if err := fs.Parse(args); err != nil {
+        return false
+    }
+    if fs.NArg() != n {
+        fmt.Fprintf(cmd.stderr, "usage: %s %s\n", fs.Name(), names)
+        return false
+    }
+    return true
+}
+
+// secretsFlag adds the --show-secrets flag to fs; unless it is given,
+// the configs loaded have the values of keys that look secret redacted.
+func (cmd *command) secretsFlag(fs *flag.FlagSet) {
+    cmd.redact = true
+    fs.BoolVar(&cmd.showSecrets, "show-secrets", false, "print passwords, tokens and other values whose keys look secret")
+}
+
+// fail reports err and returns the exit status for it.
+func (cmd *command) fail(err error, status int) int {
+    fmt.Fprintf(cmd.stderr, "hocon: %v\n", err)
+    return status
+}
+
+// load parses the named file, marking its secrets before resolving it if
+// the command redacts them. A read error is reported with exitUsage, a
+// parse error with exitFailure.
+func (cmd *command) load(name string, resolve bool) (*parse.Config, int) {
+    var tree *parse.Tree
+    var err error
+    if name == "-" {
+        text, rerr := ioutil.ReadAll(cmd.stdin)
+        if rerr != nil {
+            return nil, cmd.fail(rerr, exitUsage)
+        }
+        tree, err = parse.Parse("stdin", string(text))
+    } else {
+        if _, serr := os.Stat(name); serr != nil {
+            return nil, cmd.fail(serr, exitUsage)
+        }
+        tree, err = parse.ParseFile(name)
+    }
+    if err != nil {
+        return nil, cmd.fail(err, exitFailure)
+    }
+    conf := tree.GetConfig()
+    if cmd.redact && !cmd.showSecrets {
+        if conf, err = conf.WithSecrets(parse.SecretKeyPatterns...); err != nil {
+            return nil, cmd.fail(err, exitFailure)
+        }
+    }
+    if resolve {
+        if conf, err = conf.Resolve(); err != nil {
+            return nil, cmd.fail(err, exitFailure)
+        }
+    }
+    return conf, exitOK
+}
+
+// replaceFile replaces the file name with data and gives it mode. It
+// writes a temporary file next to it and renames that over it, so that
+// name holds either its old or its new contents, never a part of them.
+func replaceFile(name string, data []byte, mode os.FileMode) error {
+    f, err := ioutil.TempFile(filepath.Dir(name), "."+filepath.Base(name)+".")
+    if err != nil {
+        return err
+    }
+    _, err = f.Write(data)
+    if err == nil {
+        err = f.Sync()
+    }
+    if cerr := f.Close(); err == nil {
+        err = cerr
+    }
+    if err == nil {
+        err = os.Chmod(f.Name(), mode)
+    }
+    if err == nil {
+        err = os.Rename(f.Name(), name)
+    }
+    if err != nil {
+        os.Remove(f.Name())
+    }
+    return err
+}
+
+// print writes conf in format to stdout.
+func (cmd *command) print(conf *parse.Config, format parse.Format) int {
+    out, err := conf.Render(format)
+    if err != nil {
+        return cmd.fail(err, exitFailure)
+    }
+    cmd.stdout.Write(out)
+    return exitOK
+}
+
+func (cmd *command) get(args []string) int {
+    fs := cmd.flags("get")
+    cmd.secretsFlag(fs)
+    if !cmd.parseArgs(fs, args, 2, "[--show-secrets] file path") {
+        return exitUsage
+    }
+    conf, status := cmd.load(fs.Arg(0), true)
+    if conf == nil {
+        return status
+    }
+    value, err := conf.GetValue(fs.Arg(1))
+    if err != nil {
+        return cmd.fail(err, exitFailure)
+    }
+    switch value.Root().Type() {
+        case parse.NodeMap, parse.NodeList:
+            return cmd.print(value, parse.FormatHOCON)
+    }
+    // Scalars are printed bare, for use in scripts, which may ask for
+    // secrets, decrypted ones included.
+    s, err := value.GetString("")
+    if err != nil || value.IsSecret("") && !cmd.showSecrets {
+        s = value.String()
+    }
+    fmt.Fprintln(cmd.stdout, s)
+    return exitOK
+}
+
+func (cmd *command) render(args []string) int {
+    fs := cmd.flags("render")
+    asJSON := fs.Bool("json", false, "render as JSON, which implies --resolve")
+    resolve := fs.Bool("resolve", false, "resolve substitutions")
+    cmd.secretsFlag(fs)
+    if !cmd.parseArgs(fs, args, 1, "[--json] [--resolve] [--show-secrets] file") {
+        return exitUsage
+    }
+    format := parse.FormatHOCON
+    if *asJSON {
+        format, *resolve = parse.FormatJSON, true
+    }
+    conf, status := cmd.load(fs.Arg(0), *resolve)
+    if conf == nil {
+        return status
+    }
+    return cmd.print(conf, format)
+}
+
+func (cmd *command) convert(args []string) int {
+    fs := cmd.flags("convert")
+    to := fs.String("to", "json", "output format: hocon, json or yaml")
+    cmd.secretsFlag(fs)
+    if !cmd.parseArgs(fs, args, 1, "[--to hocon|json|yaml] [--show-secrets] file") {
+        return exitUsage
+    }
+    format, err := parse.ParseFormat(*to)
+    if err != nil {
+        return cmd.fail(err, exitUsage)
+    }
+    conf, status := cmd.load(fs.Arg(0), true)
+    if conf == nil {
+        return status
+    }
+    return cmd.print(conf, format)
+}
+
+func (cmd *command) validate(args []string) int {
+    fs := cmd.flags("validate")
+    reference := fs.String("reference", "", "reference config with the defaults of every setting")
+    schemaFile := fs.String("schema", "", "schema describing the settings")
+    jsonSchemaFile := fs.String("json-schema", "", "JSON Schema describing the settings")
+    if !cmd.parseArgs(fs, args, 1, "[--reference ref.conf] [--schema schema.conf] [--json-schema schema.json] file") {
+        return exitUsage
+    }
+    conf, status := cmd.load(fs.Arg(0), false)
+    if conf == nil {
+        return status
+    }
+    var schema *parse.Schema
+    if *schemaFile != "" {
+        s, status := cmd.load(*schemaFile, true)
+        if s == nil {
+            return status
+        }
+        var err error
+        if schema, err = parse.NewSchema(s); err != nil {
+            return cmd.fail(err, exitFailure)
+        }
+    }
+    var jsonSchema *parse.JSONSchema
+    if *jsonSchemaFile != "" {
+        data, err := ioutil.ReadFile(*jsonSchemaFile)
+        if err != nil {
+            return cmd.fail(err, exitUsage)
+        }
+        if jsonSchema, err = parse.ParseJSONSchema(data); err != nil {
+            return cmd.fail(fmt.Errorf("%s: %v", *jsonSchemaFile, err), exitFailure)
+        }
+    }
+    var ref *parse.Config
+    if *reference != "" {
+        if ref, status = cmd.load(*reference, false); ref == nil {
+            return status
+        }
+        // Like an application would, use the reference as the fallback
+        // and resolve the two together.
+        conf = conf.WithFallback(ref)
+    }
+    conf, err := conf.Resolve()
+    if err != nil {
+        return cmd.fail(err, exitFailure)
+    }
+    failed := false
+    if ref != nil {
+        if ref, err = ref.Resolve(); err != nil {
+            return cmd.fail(err, exitFailure)
+        }
+        failed = cmd.report(conf.CheckValid(ref))
+    }
+    if schema != nil {
+        failed = cmd.report(parse.Validate(conf, schema)) || failed
+    }
+    if jsonSchema != nil {
+        failed = cmd.report(jsonSchema.Validate(conf)) || failed
+    }
+    if failed {
+        return exitFailure
+    }
+    return exitOK
+}
+
+// report prints the problems of a validation, one per line, and returns
+// whether there were any.
+func (cmd *command) report(err error) bool {
+    if err == nil {
+        return false
+    }
+    if errs, ok := err.(parse.ValidationErrors); ok {
+        for _, e := range errs {
+            fmt.Fprintln(cmd.stderr, e)
+        }
+    } else {
+        cmd.fail(err, exitFailure)
+    }
+    return true
+}
+
+// jsonSchema prints a JSON Schema of a reference config, with the
+// comments of its settings as their descriptions.
+func (cmd *command) jsonSchema(args []string) int {
+    fs := cmd.flags("jsonschema")
+    if !cmd.parseArgs(fs, args, 1, "ref.conf") {
+        return exitUsage
+    }
+    name := fs.Arg(0)
+    var text []byte
+    var err error
+    if name == "-" {
+        text, err = ioutil.ReadAll(cmd.stdin)
+        cmd.stdin = bytes.NewReader(text)
+    } else {
+        text, err = ioutil.ReadFile(name)
+    }
+    if err != nil {
+        return cmd.fail(err, exitUsage)
+    }
+    conf, status := cmd.load(name, true)
+    if conf == nil {
+        return status
+    }
+    out, err := conf.ToJSONSchema(parse.Descriptions(name, string(text)))
+    if err != nil {
+        return cmd.fail(err, exitFailure)
+    }
+    cmd.stdout.Write(out)
+    return exitOK
+}
+
+func (cmd *command) diff(args []string) int {
+    fs := cmd.flags("diff")
+    cmd.secretsFlag(fs)
+    if !cmd.parseArgs(fs, args, 2, "[--show-secrets] a.conf b.conf") {
+        return exitUsage
+    }
+    a, status := cmd.load(fs.Arg(0), true)
+    if a == nil {
+        return status
+    }
+    b, status := cmd.load(fs.Arg(1), true)
+    if b == nil {
+        return status
+    }
+    changes := parse.Diff(a, b)
+    for _, c := range changes {
+        fmt.Fprintln(cmd.stdout, c)
+    }
+    if len(changes) > 0 {
+        return exitFailure
+    }
+    return exitOK
+}
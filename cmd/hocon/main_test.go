@@ -0,0 +1,242 @@
+package main
+
+import (
+    "bytes"
+    "io/ioutil"
+    "math/rand"
+    "os"
+    "path/filepath"
+    "strconv"
+    "strings"
+    "testing"
+
+    "github.com/liyinhgqw/typesafe-config/parse"
+)
+
+var files = map[string]string{
+    "app.conf": `include "ref.conf"
+akka.remote.port = 2553
+akka.loglevel = ${level}
+level = DEBUG`,
+    "ref.conf": `akka { remote { port = 2552, host = localhost }, loglevel = INFO }`,
+    "bad.conf":    `akka { remote.port = true }`,
+    "broken.conf": `akka {`,
+    "fmt.conf":    "a:1\nb {c=2}\n",
+    "write.conf":  "a:1\n",
+    "ok.conf":     "a = 1\n",
+    "schema.conf": "akka.remote.port { type = int, max = 2560 }",
+    "port.conf":   "akka.remote.port = 3000",
+    "schema.json": `{"properties": {"akka": {"properties": {"remote": {"properties": {"port": {"type": "integer", "maximum": 2560}}}}}}}`,
+    "doc.conf":    "# The port.\nport = 80\n",
+    "secret.conf": "db { user = app, password = hunter2 }",
+    "secret2.conf": "db { user = app, password = swordfish }",
+    "keys":        "k:AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=\n",
+    "enc.conf":    "db.password = hunter2 # to encrypt\n",
+}
+
+type runTest struct {
+    name   string
+    args   []string
+    status int
+    stdout string
+    stderr string // a substring of the standard error
+}
+
+var runTests = []runTest{
+    {"no command", nil, exitUsage, "", "usage"},
+    {"unknown command", []string{"frob"}, exitUsage, "", "unknown command"},
+    {"get", []string{"get", "app.conf", "akka.remote.port"}, exitOK, "2553\n", ""},
+    {"get substituted", []string{"get", "app.conf", "akka.loglevel"}, exitOK, "DEBUG\n", ""},
+    {"get object", []string{"get", "app.conf", "akka.remote"}, exitOK, "host = \"localhost\"\nport = 2553\n", ""},
+    {"get missing", []string{"get", "app.conf", "akka.nothing"}, exitFailure, "", "no such key"},
+    {"get no file", []string{"get", "nothing.conf", "a"}, exitUsage, "", "nothing.conf"},
+    {"get usage", []string{"get", "app.conf"}, exitUsage, "", "usage"},
+    {"get stdin", []string{"get", "-", "a"}, exitOK, "1\n", ""},
+    {"render", []string{"render", "ref.conf"}, exitOK,
+        "akka {\n  loglevel = \"INFO\"\n  remote {\n    host = \"localhost\"\n    port = 2552\n  }\n}\n", ""},
+    {"render unresolved", []string{"render", "app.conf"}, exitOK, "", ""},
+    {"render json", []string{"render", "--json", "bad.conf"}, exitOK,
+        "{\n  \"akka\": {\n    \"remote\": {\n      \"port\": true\n    }\n  }\n}\n", ""},
+    {"render broken", []string{"render", "broken.conf"}, exitFailure, "", "broken.conf"},
+    {"convert yaml", []string{"convert", "--to", "yaml", "bad.conf"}, exitOK, "akka:\n  remote:\n    port: true\n", ""},
+    {"render redacted", []string{"render", "secret.conf"}, exitOK,
+        "db {\n  password = \"<redacted>\"\n  user = \"app\"\n}\n", ""},
+    {"render secrets", []string{"render", "--show-secrets", "secret.conf"}, exitOK,
+        "db {\n  password = \"hunter2\"\n  user = \"app\"\n}\n", ""},
+    {"convert redacted", []string{"convert", "--to", "yaml", "secret.conf"}, exitOK,
+        "db:\n  password: \"<redacted>\"\n  user: \"app\"\n", ""},
+    {"get redacted", []string{"get", "secret.conf", "db.password"}, exitOK, "<redacted>\n", ""},
+    {"get secret", []string{"get", "--show-secrets", "secret.conf", "db.password"}, exitOK, "hunter2\n", ""},
+    {"diff redacted", []string{"diff", "secret.conf", "secret2.conf"}, exitFailure,
+        "~ db.password = <redacted> (secret.conf:1:29) -> <redacted> (secret2.conf:1:29)\n", ""},
+    {"diff secrets", []string{"diff", "--show-secrets", "secret.conf", "secret2.conf"}, exitFailure,
+        "~ db.password = hunter2 (secret.conf:1:29) -> swordfish (secret2.conf:1:29)\n", ""},
+    {"convert unknown", []string{"convert", "--to", "xml", "bad.conf"}, exitUsage, "", "unknown format"},
+    {"validate", []string{"validate", "app.conf"}, exitOK, "", ""},
+    {"validate reference", []string{"validate", "--reference", "ref.conf", "app.conf"}, exitOK, "", ""},
+    {"validate invalid", []string{"validate", "--reference", "ref.conf", "bad.conf"}, exitFailure, "",
+        "akka.remote.port: expected number, got boolean"},
+    {"validate schema", []string{"validate", "--schema", "schema.conf", "ref.conf"}, exitOK, "", ""},
+    {"validate schema invalid", []string{"validate", "--schema", "schema.conf", "--reference", "ref.conf", "bad.conf"}, exitFailure, "",
+        "akka.remote.port: expected int, got boolean"},
+    {"validate schema range", []string{"validate", "--schema", "schema.conf", "port.conf"}, exitFailure, "",
+        "port.conf:1:20: akka.remote.port: 3000 is more than the maximum 2560"},
+    {"validate json schema", []string{"validate", "--json-schema", "schema.json", "ref.conf"}, exitOK, "", ""},
+    {"validate json schema range", []string{"validate", "--json-schema", "schema.json", "port.conf"}, exitFailure, "",
+        "port.conf:1:20: akka.remote.port: 3000 is more than the maximum 2560"},
+    {"validate json schema broken", []string{"validate", "--json-schema", "ok.conf", "port.conf"}, exitFailure, "", "ok.conf: JSON Schema"},
+    {"validate broken", []string{"validate", "broken.conf"}, exitFailure, "", "broken.conf"},
+    {"jsonschema", []string{"jsonschema", "doc.conf"}, exitOK, `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "properties": {
+    "port": {
+      "default": 80,
+      "description": "The port.",
+      "type": "integer"
+    }
+  },
+  "type": "object"
+}
+`, ""},
+    {"jsonschema unresolved", []string{"jsonschema", "app.conf"}, exitOK, "", ""},
+    {"diff same", []string{"diff", "ref.conf", "ref.conf"}, exitOK, "", ""},
+    {"diff", []string{"diff", "ref.conf", "bad.conf"}, exitFailure,
+        "- akka.loglevel = INFO (ref.conf:1:61)\n" +
+        "- akka.remote.host = localhost (ref.conf:1:37)\n" +
+        "~ akka.remote.port = 2552 (ref.conf:1:24) -> true (bad.conf:1:22)\n", ""},
+    {"fmt", []string{"fmt", "fmt.conf"}, exitOK, "a = 1\nb { c = 2 }\n", ""},
+    {"fmt stdin", []string{"fmt", "--colon"}, exitOK, "a: 1\n", ""},
+    {"fmt list", []string{"fmt", "-l", "fmt.conf", "ok.conf"}, exitFailure, "fmt.conf\n", ""},
+    {"fmt list formatted", []string{"fmt", "-l", "ok.conf"}, exitOK, "", ""},
+    {"fmt diff", []string{"fmt", "-d", "fmt.conf"}, exitFailure,
+        "--- fmt.conf.orig\n+++ fmt.conf\n@@ -1,2 +1,2 @@\n-a:1\n-b {c=2}\n+a = 1\n+b { c = 2 }\n", ""},
+    {"fmt broken", []string{"fmt", "broken.conf"}, exitFailure, "", "broken.conf"},
+    {"fmt write", []string{"fmt", "-w", "write.conf"}, exitOK, "", ""},
+    {"fmt written", []string{"fmt", "-l", "write.conf"}, exitOK, "", ""},
+    {"encrypt no keyfile", []string{"encrypt", "--keyfile", "nothing", "enc.conf", "db.password"}, exitUsage, "", "nothing"},
+    {"encrypt", []string{"encrypt", "enc.conf", "db.password"}, exitOK, "", ""},
+    {"encrypt again", []string{"encrypt", "--keyfile", "keys", "enc.conf", "db.password"}, exitFailure, "", "already encrypted"},
+    {"encrypt missing", []string{"encrypt", "enc.conf", "db.user"}, exitFailure, "", "db.user is not set"},
+    {"get decrypted", []string{"get", "--show-secrets", "enc.conf", "db.password"}, exitOK, "hunter2\n", ""},
+    {"convert decrypted", []string{"convert", "--to", "hocon", "enc.conf"}, exitOK, "db {\n  password = \"<redacted>\"\n}\n", ""},
+    {"keygen", []string{"keygen", "--name", "k2"}, exitOK, "", ""},
+    {"keygen bad name", []string{"keygen", "--name", "a:b"}, exitUsage, "", "bad key name"},
+    {"fmt write stdin", []string{"fmt", "-w"}, exitUsage, "", "standard input"},
+}
+
+func TestRun(t *testing.T) {
+    dir, err := ioutil.TempDir("", "hocon")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer os.RemoveAll(dir)
+    for name, text := range files {
+        if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(text), 0644); err != nil {
+            t.Fatal(err)
+        }
+    }
+    wd, _ := os.Getwd()
+    if err := os.Chdir(dir); err != nil {
+        t.Fatal(err)
+    }
+    defer os.Chdir(wd)
+    os.Setenv(parse.KeyfileEnv, "keys")
+    defer os.Unsetenv(parse.KeyfileEnv)
+    for _, test := range runTests {
+        var stdout, stderr bytes.Buffer
+        status := run(test.args, strings.NewReader("a = 1"), &stdout, &stderr)
+        if status != test.status {
+            t.Errorf("%s: got status %d, expected %d; stderr: %s", test.name, status, test.status, &stderr)
+        }
+        if test.stdout != "" && stdout.String() != test.stdout {
+            t.Errorf("%s: got output\n%s\nexpected\n%s", test.name, &stdout, test.stdout)
+        }
+        if !strings.Contains(stderr.String(), test.stderr) {
+            t.Errorf("%s: got errors %q, expected %q in them", test.name, &stderr, test.stderr)
+        }
+    }
+
+    // encrypt and fmt -w replace files whole, keeping their mode.
+    for _, name := range []string{"enc.conf", "write.conf"} {
+        if info, err := os.Stat(name); err != nil || info.Mode().Perm() != 0644 {
+            t.Errorf("%s: %v, %v", name, info.Mode(), err)
+        }
+    }
+    entries, _ := ioutil.ReadDir(".")
+    for _, e := range entries {
+        if strings.HasPrefix(e.Name(), ".") {
+            t.Errorf("temporary file %s left behind", e.Name())
+        }
+    }
+}
+
+func TestLineDiff(t *testing.T) {
+    // lcs is the length of a longest common subsequence of a and b.
+    lcs := func(a, b []string) int {
+        l := make([][]int, len(a)+1)
+        for i := range l {
+            l[i] = make([]int, len(b)+1)
+        }
+        for i := len(a) - 1; i >= 0; i-- {
+            for j := len(b) - 1; j >= 0; j-- {
+                switch {
+                    case a[i] == b[j]:
+                        l[i][j] = l[i+1][j+1] + 1
+                    case l[i+1][j] > l[i][j+1]:
+                        l[i][j] = l[i+1][j]
+                    default:
+                        l[i][j] = l[i][j+1]
+                }
+            }
+        }
+        return l[0][0]
+    }
+    rnd := rand.New(rand.NewSource(1))
+    random := func() []string {
+        lines := make([]string, rnd.Intn(12))
+        for i := range lines {
+            lines[i] = string(rune('a' + rnd.Intn(4)))
+        }
+        return lines
+    }
+    for i := 0; i < 1000; i++ {
+        a, b := random(), random()
+        var gotA, gotB []string
+        changed := 0
+        for _, e := range lineDiff(a, b) {
+            if e.kind != '+' {
+                gotA = append(gotA, e.text)
+            }
+            if e.kind != '-' {
+                gotB = append(gotB, e.text)
+            }
+            if e.kind != ' ' {
+                changed++
+            }
+        }
+        if strings.Join(gotA, "") != strings.Join(a, "") || strings.Join(gotB, "") != strings.Join(b, "") {
+            t.Fatalf("diff of %q and %q gives %q and %q", a, b, gotA, gotB)
+        }
+        if expect := len(a) + len(b) - 2*lcs(a, b); changed != expect {
+            t.Fatalf("diff of %q and %q has %d changes, expected %d", a, b, changed, expect)
+        }
+    }
+
+    // A few changes in a large file.
+    a := make([]string, 50000)
+    for i := range a {
+        a[i] = strconv.Itoa(i)
+    }
+    b := append([]string{"new"}, a...)
+    b[25000] = "changed"
+    if edits := lineDiff(a, b); len(edits) != 50002 {
+        t.Errorf("got %d edits, expected 50002", len(edits))
+    }
+    // Too different to diff line by line.
+    for i := range b {
+        b[i] += "x"
+    }
+    if edits := lineDiff(a, b); len(edits) != 100001 || edits[0].kind != '-' || edits[50000].kind != '+' {
+        t.Errorf("got %d edits, expected all lines replaced", len(edits))
+    }
+}
@@ -0,0 +1,31 @@
+package parse
+
+import (
+    "sync/atomic"
+)
+
+// AtomicConfig holds a *Config that can be replaced while other goroutines
+// read it, such as the current config of a service that reloads it. The
+// zero value holds nil. An AtomicConfig must not be copied after first use.
+type AtomicConfig struct {
+    v atomic.Value
+}
+
+// NewAtomicConfig returns an AtomicConfig holding c.
+func NewAtomicConfig(c *Config) *AtomicConfig {
+    a := new(AtomicConfig)
+    a.Store(c)
+    return a
+}
+
+// Load returns the config last stored, or nil if there is none.
+func (a *AtomicConfig) Load() *Config {
+    c, _ := a.v.Load().(*Config)
+    return c
+}
+
+// Store replaces the config held by a. Goroutines that loaded the previous
+// config keep using it unchanged.
+func (a *AtomicConfig) Store(c *Config) {
+    a.v.Store(c)
+}
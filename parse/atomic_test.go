@@ -0,0 +1,72 @@
+package parse
+
+import (
+    "fmt"
+    "sync"
+    "testing"
+)
+
+func TestWithFallback(t *testing.T) {
+    conf := testConfig(t, `a { b = 1, c { d = 1 } }, e = 1`)
+    fallback := testConfig(t, `a { b = 2, c { f = 2 }, g = 2 }, h = 2`)
+    before, fallbackBefore := conf.String(), fallback.String()
+    merged := conf.WithFallback(fallback)
+    expect := `a = (b = (1)c = (d = (1)f = (2))g = (2))e = (1)h = (2)`
+    if s := merged.String(); s != expect {
+        t.Errorf("got\n\t%s\nexpected\n\t%s", s, expect)
+    }
+    if conf.String() != before || fallback.String() != fallbackBefore {
+        t.Errorf("WithFallback changed its inputs: %s and %s", conf, fallback)
+    }
+    scalar := testConfig(t, `a = 1`)
+    a, _ := scalar.GetValue("a")
+    if s := a.WithFallback(conf).String(); s != "1" {
+        t.Errorf("a scalar with a fallback is not itself")
+    }
+}
+
+// TestConcurrentAccess reads configs while other goroutines merge,
+// resolve and swap them. It finds nothing without the race detector.
+func TestConcurrentAccess(t *testing.T) {
+    base := testConfig(t, `a { b = 1, c = [1, 2] }, d = ${a.b}`)
+    current := NewAtomicConfig(base)
+    var wg sync.WaitGroup
+    for i := 0; i < 4; i++ {
+        wg.Add(2)
+        go func(i int) {
+            defer wg.Done()
+            for j := 0; j < 50; j++ {
+                override, err := Parse("override", fmt.Sprintf(`a { b = %d, e = %d }`, i, j))
+                if err != nil {
+                    t.Error(err)
+                    return
+                }
+                merged := override.GetConfig().WithFallback(current.Load())
+                resolved, err := merged.Resolve()
+                if err != nil {
+                    t.Error(err)
+                    return
+                }
+                current.Store(resolved)
+            }
+        }(i)
+        go func() {
+            defer wg.Done()
+            for j := 0; j < 50; j++ {
+                for _, conf := range []*Config{base, current.Load()} {
+                    conf.GetInt("a.b")
+                    conf.Hash()
+                    conf.Entries()
+                    Diff(base, conf)
+                }
+            }
+        }()
+    }
+    wg.Wait()
+    if s := base.String(); s != `a = (b = (1)c = (12))d = (${a.b})` {
+        t.Errorf("base config changed: %s", s)
+    }
+    if _, err := current.Load().GetInt("a.e"); err != nil {
+        t.Errorf("last stored config: %v", err)
+    }
+}
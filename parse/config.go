@@ -1,33 +1,110 @@
 package parse
-import "strings"
-import "errors"
+import (
+    "errors"
+    "fmt"
+    "math"
+    "math/big"
+    "strings"
+    "time"
+)
 
+// Config is a parsed configuration, or a value taken from one.
+//
+// A Config is immutable: once Parse has returned, no method of Config and
+// no function of this package changes the nodes it refers to. Resolve,
+// WithFallback and the other methods returning a Config build new nodes
+// where they need to and share the rest. A Config may therefore be read
+// from many goroutines at once, while others derive new configs from it.
+// Code that modifies nodes reached through Root or Tree.Root gives up
+// this guarantee.
 type Config struct {
-    root Node
+    root   Node
+    strict bool // disable the automatic conversions between strings and other scalars
 }
 
+// WithStrictTypes returns a copy of c, and of every value taken from it,
+// with the automatic type conversions turned off or on. Conversions are on
+// by default: numbers and booleans convert to strings, numeric strings to
+// numbers, and "true", "yes", "on", "false", "no" and "off" to booleans.
+func (c *Config) WithStrictTypes(strict bool) *Config {
+    return &Config{root: c.root, strict: strict}
+}
+
+// wrap returns the value n with the same settings as c.
+func (c *Config) wrap(n Node) *Config {
+    return &Config{root: n, strict: c.strict}
+}
+
+// WithFallback returns c merged over other: the keys of c, and those of
+// other that c lacks, with objects under the same key merged likewise.
+// If c is not an object, or other is not one, the result is c. Neither c
+// nor other is changed.
+func (c *Config) WithFallback(other *Config) *Config {
+    if c.root == nil {
+        return other
+    }
+    if other.root == nil {
+        return c
+    }
+    return c.wrap(c.root.withFallback(other.root))
+}
+
+// GetValue returns the value at path. Path segments are separated by dots
+// and may be quoted to contain dots themselves; list elements are selected
+// by index, either as a segment or in brackets: "seed-nodes.0" and
+// "seed-nodes[0]" are equivalent. The empty path is c itself.
+// A failed lookup returns a *PathError naming the offending segment.
 func (c *Config) GetValue(path string) (conf *Config, err error) {
-    ps := strings.Split(path, ".")
-    if (len(ps) == 0) {
-        err = errors.New("empty path")
+    ps, err := parsePath(path)
+    if err != nil {
         return
-    } else {
-        v := c.root
-        for i := 0; i < len(ps); i++ {
-            key := ps[i]
-            if (v.Type() == NodeMap) {
-                node, _ := v.(*MapNode)
-                if n, ok := node.Nodes[key]; !ok {
-                    err = errors.New("path not valid: " + key)
-                    return
-                } else {
-                    v = n
-                }
-            }
-        }
-        conf = &Config{root: v}
+    }
+    if c.root == nil {
+        err = &PathError{Path: path, Err: ErrMissing}
+        return
+    }
+    v, err := lookup(c.root, path, ps)
+    if err != nil {
+        return
+    }
+    conf = c.wrap(v)
+    return
+}
+
+// HasPath reports whether path exists and is not null.
+func (c *Config) HasPath(path string) bool {
+    conf, err := c.GetValue(path)
+    return err == nil && conf.root.Type() != NodeNil
+}
+
+// HasPathOrNull reports whether path exists, even if it is set to null.
+func (c *Config) HasPathOrNull(path string) bool {
+    _, err := c.GetValue(path)
+    return err == nil
+}
+
+// IsNull reports whether the value at path is null. It returns an error if
+// the path does not exist.
+func (c *Config) IsNull(path string) (null bool, err error) {
+    conf, err := c.GetValue(path)
+    if err != nil {
         return
     }
+    null = conf.root.Type() == NodeNil
+    return
+}
+
+// IsEmpty reports whether c is an object without keys or an empty list.
+func (c *Config) IsEmpty() bool {
+    switch n := c.root.(type) {
+        case nil:
+            return true
+        case *MapNode:
+            return len(n.Nodes) == 0
+        case *ListNode:
+            return len(n.Nodes) == 0
+    }
+    return false
 }
 
 func (c *Config) String() string {
@@ -39,138 +116,430 @@ func (c *Config) GetString(path string) (val string, err error) {
     if err != nil {
         return
     }
-    if conf.root.Type() == NodeString {
-        if cstr, ok := conf.root.(*StringNode); ok {
-            val = cstr.Quoted
-        } else {
-            err = errors.New("not valid string: " + cstr.String())
+    return c.stringValue(conf.root, path)
+}
+
+func (c *Config) GetBool(path string) (val bool, err error) {
+    conf, err := c.GetValue(path)
+    if err != nil {
+        return
+    }
+    return c.boolValue(conf.root, path)
+}
+
+func (c *Config) GetInt(path string) (val int64, err error) {
+    conf, err := c.GetValue(path)
+    if err != nil {
+        return
+    }
+    return c.intValue(conf.root, path)
+}
+
+func (c *Config) GetUInt(path string) (val uint64, err error) {
+    conf, err := c.GetValue(path)
+    if err != nil {
+        return
+    }
+    return c.uintValue(conf.root, path)
+}
+
+func (c *Config) GetFloat(path string) (val float64, err error) {
+    conf, err := c.GetValue(path)
+    if err != nil {
+        return
+    }
+    return c.floatValue(conf.root, path)
+}
+
+// GetInt32 returns the integer at path, checking that it fits in an int32.
+func (c *Config) GetInt32(path string) (val int32, err error) {
+    i, err := c.GetInt(path)
+    if err != nil {
+        return
+    }
+    if i < math.MinInt32 || i > math.MaxInt32 {
+        return 0, errors.New("int32 out of range: " + path)
+    }
+    return int32(i), nil
+}
+
+// GetUint32 returns the unsigned integer at path, checking that it fits
+// in a uint32.
+func (c *Config) GetUint32(path string) (val uint32, err error) {
+    u, err := c.GetUInt(path)
+    if err != nil {
+        return
+    }
+    if u > math.MaxUint32 {
+        return 0, errors.New("uint32 out of range: " + path)
+    }
+    return uint32(u), nil
+}
+
+// GetFloat32 returns the number at path, checking that it fits in a
+// float32.
+func (c *Config) GetFloat32(path string) (val float32, err error) {
+    f, err := c.GetFloat(path)
+    if err != nil {
+        return
+    }
+    if math.Abs(f) > math.MaxFloat32 && !math.IsInf(f, 0) {
+        return 0, errors.New("float32 out of range: " + path)
+    }
+    return float32(f), nil
+}
+
+func (c *Config) GetComplex(path string) (val complex128, err error) {
+    conf, err := c.GetValue(path)
+    if err != nil {
+        return
+    }
+    return c.complexValue(conf.root, path)
+}
+
+// GetBigInt returns the integer at path with arbitrary precision.
+func (c *Config) GetBigInt(path string) (val *big.Int, err error) {
+    conf, err := c.GetValue(path)
+    if err != nil {
+        return
+    }
+    if cnum, ok := c.numberValue(conf.root); ok && cnum.BigInt != nil {
+        return new(big.Int).Set(cnum.BigInt), nil
+    }
+    return nil, errors.New("not valid big.Int: " + path)
+}
+
+// GetBigFloat returns the number at path with arbitrary precision. Binary
+// floating point cannot represent all decimals exactly; use GetNumberText
+// for the exact decimal value.
+func (c *Config) GetBigFloat(path string) (val *big.Float, err error) {
+    conf, err := c.GetValue(path)
+    if err != nil {
+        return
+    }
+    if cnum, ok := c.numberValue(conf.root); ok && cnum.BigFloat != nil {
+        return new(big.Float).Copy(cnum.BigFloat), nil
+    }
+    return nil, errors.New("not valid big.Float: " + path)
+}
+
+// GetNumberText returns the number at path exactly as it was written.
+func (c *Config) GetNumberText(path string) (val string, err error) {
+    conf, err := c.GetValue(path)
+    if err != nil {
+        return
+    }
+    if cnum, ok := c.numberValue(conf.root); ok {
+        return cnum.Text, nil
+    }
+    return "", errors.New("not valid number: " + path)
+}
+
+// GetDuration returns the duration at path. It is either a number of
+// milliseconds or a number followed by a unit, such as "100 s" or "9ms".
+func (c *Config) GetDuration(path string) (val time.Duration, err error) {
+    conf, err := c.GetValue(path)
+    if err != nil {
+        return
+    }
+    return c.durationValue(conf.root, path)
+}
+
+// GetBytes returns the size in bytes at path. It is either a number of
+// bytes or a number followed by a unit, such as "512 KiB" or "10MB".
+func (c *Config) GetBytes(path string) (val int64, err error) {
+    conf, err := c.GetValue(path)
+    if err != nil {
+        return
+    }
+    return c.bytesValue(conf.root, path)
+}
+
+func (c *Config) GetArray(path string) (vals []*Config, err error) {
+    conf, err := c.GetValue(path)
+    if err != nil {
+        return
+    }
+    if clist, ok := conf.root.(*ListNode); ok {
+        for _, n := range clist.Nodes {
+            vals = append(vals, c.wrap(n))
         }
     } else {
-        err = errors.New("not valid string: " + path)
+        err = errors.New("not valid list node: " + path)
     }
     return
 }
 
-func (c *Config) GetBool(path string) (val bool, err error) {
+// list returns the elements of the list at path.
+func (c *Config) list(path string) (nodes []Node, err error) {
     conf, err := c.GetValue(path)
     if err != nil {
         return
     }
-    if conf.root.Type() == NodeBool {
-        if cbool, ok := conf.root.(*BoolNode); ok {
-            val = cbool.True
-        } else {
-            err = errors.New("not valid bool: " + cbool.String())
-        }
+    if clist, ok := conf.root.(*ListNode); ok {
+        nodes = clist.Nodes
     } else {
-        err = errors.New("not valid bool: " + path)
+        err = errors.New("not valid list node: " + path)
     }
     return
 }
 
-func (c *Config) GetInt(path string) (val int64, err error) {
-    conf, err := c.GetValue(path)
+// elemPath names the i'th element of the list at path in error messages,
+// as GetValue takes it.
+func elemPath(path string, i int) string {
+    return fmt.Sprintf("%s.%d", path, i)
+}
+
+func (c *Config) GetStringList(path string) (vals []string, err error) {
+    nodes, err := c.list(path)
     if err != nil {
         return
     }
-    if conf.root.Type() == NodeNumber {
-        if cnum, ok := conf.root.(*NumberNode); ok {
-            switch {
-                case cnum.IsInt:
-                    val = cnum.Int64
-                default:
-                    err = errors.New("not valid int64: " + cnum.String())
-            }
-        } else {
-            err = errors.New("not valid int64: " + cnum.String())
+    vals = make([]string, len(nodes))
+    for i, n := range nodes {
+        if vals[i], err = c.stringValue(n, elemPath(path, i)); err != nil {
+            return nil, err
         }
-    } else {
-        err = errors.New("not valid int64: " + path)
     }
     return
 }
 
-func (c *Config) GetUInt(path string) (val uint64, err error) {
-    conf, err := c.GetValue(path)
+func (c *Config) GetBoolList(path string) (vals []bool, err error) {
+    nodes, err := c.list(path)
     if err != nil {
         return
     }
-    if conf.root.Type() == NodeNumber {
-        if cnum, ok := conf.root.(*NumberNode); ok {
-            switch {
-                case cnum.IsUint:
-                    val = cnum.Uint64
-                default:
-                err = errors.New("not valid uint64: " + cnum.String())
-            }
-        } else {
-            err = errors.New("not valid uint64: " + cnum.String())
+    vals = make([]bool, len(nodes))
+    for i, n := range nodes {
+        if vals[i], err = c.boolValue(n, elemPath(path, i)); err != nil {
+            return nil, err
         }
-    } else {
-        err = errors.New("not valid uint64: " + path)
     }
     return
 }
 
-func (c *Config) GetFloat(path string) (val float64, err error) {
-    conf, err := c.GetValue(path)
+func (c *Config) GetIntList(path string) (vals []int64, err error) {
+    nodes, err := c.list(path)
     if err != nil {
         return
     }
-    if conf.root.Type() == NodeNumber {
-        if cnum, ok := conf.root.(*NumberNode); ok {
-            switch {
-                case cnum.IsFloat:
-                    val = cnum.Float64
-                default:
-                err = errors.New("not valid float64: " + cnum.String())
-            }
-        } else {
-            err = errors.New("not valid float64: " + cnum.String())
+    vals = make([]int64, len(nodes))
+    for i, n := range nodes {
+        if vals[i], err = c.intValue(n, elemPath(path, i)); err != nil {
+            return nil, err
         }
-    } else {
-        err = errors.New("not valid float64: " + path)
     }
     return
 }
 
-func (c *Config) GetComplex(path string) (val complex128, err error) {
-    conf, err := c.GetValue(path)
+func (c *Config) GetUIntList(path string) (vals []uint64, err error) {
+    nodes, err := c.list(path)
     if err != nil {
         return
     }
-    if conf.root.Type() == NodeNumber {
-        if cnum, ok := conf.root.(*NumberNode); ok {
-            switch {
-                case cnum.IsComplex:
-                    val = cnum.Complex128
-                default:
-                err = errors.New("not valid complex: " + cnum.String())
-            }
-        } else {
-            err = errors.New("not valid complex: " + cnum.String())
+    vals = make([]uint64, len(nodes))
+    for i, n := range nodes {
+        if vals[i], err = c.uintValue(n, elemPath(path, i)); err != nil {
+            return nil, err
         }
-    } else {
-        err = errors.New("not valid complex: " + path)
     }
     return
 }
 
-func (c *Config) GetArray(path string) (vals []*Config, err error) {
-    conf, err := c.GetValue(path)
+func (c *Config) GetFloatList(path string) (vals []float64, err error) {
+    nodes, err := c.list(path)
     if err != nil {
         return
     }
-    if conf.root.Type() == NodeList {
-        if clist, ok := conf.root.(*ListNode); ok {
-            for _, n := range clist.Nodes {
-                vals = append(vals, &Config{root: n})
+    vals = make([]float64, len(nodes))
+    for i, n := range nodes {
+        if vals[i], err = c.floatValue(n, elemPath(path, i)); err != nil {
+            return nil, err
+        }
+    }
+    return
+}
+
+func (c *Config) GetDurationList(path string) (vals []time.Duration, err error) {
+    nodes, err := c.list(path)
+    if err != nil {
+        return
+    }
+    vals = make([]time.Duration, len(nodes))
+    for i, n := range nodes {
+        if vals[i], err = c.durationValue(n, elemPath(path, i)); err != nil {
+            return nil, err
+        }
+    }
+    return
+}
+
+func (c *Config) GetBytesList(path string) (vals []int64, err error) {
+    nodes, err := c.list(path)
+    if err != nil {
+        return
+    }
+    vals = make([]int64, len(nodes))
+    for i, n := range nodes {
+        if vals[i], err = c.bytesValue(n, elemPath(path, i)); err != nil {
+            return nil, err
+        }
+    }
+    return
+}
+
+// GetConfigList returns the list of objects at path.
+func (c *Config) GetConfigList(path string) (vals []*Config, err error) {
+    nodes, err := c.list(path)
+    if err != nil {
+        return
+    }
+    vals = make([]*Config, len(nodes))
+    for i, n := range nodes {
+        if n.Type() != NodeMap {
+            return nil, errors.New("not valid object: " + elemPath(path, i))
+        }
+        vals[i] = c.wrap(n)
+    }
+    return
+}
+
+// Value conversions shared by the getters. The path is only used in
+// error messages. Unless c is strict, scalars are converted following the
+// HOCON rules: a number or boolean is also a string, and a string is also
+// a number or a boolean if it reads as one.
+
+func (c *Config) stringValue(n Node, path string) (val string, err error) {
+    switch v := n.(type) {
+        case *StringNode:
+            return v.Text, nil
+        case *NumberNode:
+            if !c.strict {
+                return v.Text, nil
             }
-        } else {
-            err = errors.New("not valid list node: " + clist.String())
+        case *BoolNode:
+            if !c.strict {
+                return fmt.Sprint(v.True), nil
+            }
+    }
+    return "", errors.New("not valid string: " + path)
+}
+
+func (c *Config) boolValue(n Node, path string) (val bool, err error) {
+    switch v := n.(type) {
+        case *BoolNode:
+            return v.True, nil
+        case *StringNode:
+            if !c.strict {
+                switch v.Text {
+                    case "true", "yes", "on":
+                        return true, nil
+                    case "false", "no", "off":
+                        return false, nil
+                }
+            }
+    }
+    return false, errors.New("not valid bool: " + path)
+}
+
+// numberValue returns n as a number, converting a numeric string.
+func (c *Config) numberValue(n Node) (*NumberNode, bool) {
+    switch v := n.(type) {
+        case *NumberNode:
+            return v, true
+        case *StringNode:
+            if !c.strict {
+                num, err := parseNumber(v.Pos, strings.TrimSpace(v.Text), itemNumber)
+                return num, err == nil
+            }
+    }
+    return nil, false
+}
+
+func (c *Config) intValue(n Node, path string) (val int64, err error) {
+    if cnum, ok := c.numberValue(n); ok {
+        switch {
+            case cnum.IsInt:
+                val = cnum.Int64
+            default:
+                err = errors.New("not valid int64: " + path)
         }
     } else {
-        err = errors.New("not valid list node: " + path)
+        err = errors.New("not valid int64: " + path)
     }
     return
-}
\ No newline at end of file
+}
+
+func (c *Config) uintValue(n Node, path string) (val uint64, err error) {
+    if cnum, ok := c.numberValue(n); ok {
+        switch {
+            case cnum.IsUint:
+                val = cnum.Uint64
+            default:
+                err = errors.New("not valid uint64: " + path)
+        }
+    } else {
+        err = errors.New("not valid uint64: " + path)
+    }
+    return
+}
+
+func (c *Config) floatValue(n Node, path string) (val float64, err error) {
+    if cnum, ok := c.numberValue(n); ok {
+        switch {
+            case cnum.IsFloat:
+                val = cnum.Float64
+            case cnum.BigFloat != nil:
+                err = errors.New("float64 out of range: " + path)
+            default:
+                err = errors.New("not valid float64: " + path)
+        }
+    } else {
+        err = errors.New("not valid float64: " + path)
+    }
+    return
+}
+
+func (c *Config) complexValue(n Node, path string) (val complex128, err error) {
+    if cnum, ok := c.numberValue(n); ok {
+        switch {
+            case cnum.IsComplex:
+                val = cnum.Complex128
+            case cnum.IsFloat:
+                val = complex(cnum.Float64, 0)
+            default:
+                err = errors.New("not valid complex: " + path)
+        }
+    } else {
+        err = errors.New("not valid complex: " + path)
+    }
+    return
+}
+
+func (c *Config) durationValue(n Node, path string) (val time.Duration, err error) {
+    switch v := n.(type) {
+        case *NumberNode:
+            if v.IsFloat {
+                return time.Duration(v.Float64 * float64(time.Millisecond)), nil
+            }
+        case *StringNode:
+            if val, err = parseDuration(v.Text); err == nil {
+                return
+            }
+    }
+    return 0, errors.New("not valid duration: " + path)
+}
+
+func (c *Config) bytesValue(n Node, path string) (val int64, err error) {
+    switch v := n.(type) {
+        case *NumberNode:
+            if v.IsInt {
+                return v.Int64, nil
+            }
+        case *StringNode:
+            if val, err = parseBytes(v.Text); err == nil {
+                return
+            }
+    }
+    return 0, errors.New("not valid bytes: " + path)
+}
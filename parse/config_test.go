@@ -0,0 +1,393 @@
+package parse
+
+import (
+    "fmt"
+    "testing"
+    "time"
+)
+
+const configInput = `
+akka {
+  loglevel = DEBUG
+  cluster {
+    seed-nodes = ["akka.tcp://ripak@127.0.0.1:2554", "akka.tcp://ripak@127.0.0.1:2555"]
+    roles = [[1, 2], [3]]
+  }
+  "a.b" = dotted
+}
+`
+
+func testConfig(t *testing.T, input string) *Config {
+    tree, err := Parse("test", input)
+    if err != nil {
+        t.Fatalf("parse: %v", err)
+    }
+    return tree.GetConfig()
+}
+
+type getValueTest struct {
+    path    string
+    result  string // String of the value, if found
+    segment string // failing segment, if not
+    err     error
+}
+
+var getValueTests = []getValueTest{
+    {"akka.loglevel", "DEBUG", "", nil},
+    {"akka.cluster.seed-nodes.1", `"akka.tcp://ripak@127.0.0.1:2555"`, "", nil},
+    {"akka.cluster.seed-nodes[0]", `"akka.tcp://ripak@127.0.0.1:2554"`, "", nil},
+    {"akka.cluster.roles[0][1]", "2", "", nil},
+    {"akka.cluster.roles.1.0", "3", "", nil},
+    {`akka."a.b"`, "dotted", "", nil},
+    {"akka.loglevel.anything.else", "", "anything", ErrNotObject},
+    {"akka.missing.key", "", "missing", ErrMissing},
+    {"akka.cluster.seed-nodes.2", "", "2", ErrIndexOutOfRange},
+    {"akka.cluster.seed-nodes.first", "", "first", ErrBadIndex},
+    {"akka..loglevel", "", ".loglevel", ErrBadPath},
+    {"akka.", "", ".", ErrBadPath},
+    {"akka.cluster.roles[x]", "", "[x]", ErrBadPath},
+    {`akka."a.b`, "", `"a.b`, ErrBadPath},
+}
+
+func TestGetValue(t *testing.T) {
+    conf := testConfig(t, configInput)
+    for _, test := range getValueTests {
+        v, err := conf.GetValue(test.path)
+        if test.err == nil {
+            if err != nil {
+                t.Errorf("%s: unexpected error: %v", test.path, err)
+            } else if v.String() != test.result {
+                t.Errorf("%s: got %s, expected %s", test.path, v, test.result)
+            }
+            continue
+        }
+        perr, ok := err.(*PathError)
+        if !ok {
+            t.Errorf("%s: expected *PathError, got %#v", test.path, err)
+            continue
+        }
+        if perr.Err != test.err || perr.Segment != test.segment {
+            t.Errorf("%s: got error %v at %q, expected %v at %q", test.path, perr.Err, perr.Segment, test.err, test.segment)
+        }
+    }
+}
+
+func TestGetValueEmptyPath(t *testing.T) {
+    conf := testConfig(t, configInput)
+    level, err := conf.GetValue("akka.loglevel")
+    if err != nil {
+        t.Fatal(err)
+    }
+    self, err := level.GetValue("")
+    if err != nil || self.String() != "DEBUG" {
+        t.Errorf("empty path: got %v, %v", self, err)
+    }
+}
+
+func TestHasPath(t *testing.T) {
+    conf := testConfig(t, `
+        a { b = 1, n = null, empty {}, list = [] }
+    `)
+    tests := []struct {
+        path          string
+        has, orNull   bool
+        null, nullErr bool
+    }{
+        {"a", true, true, false, false},
+        {"a.b", true, true, false, false},
+        {"a.n", false, true, true, false},
+        {"a.missing", false, false, false, true},
+        {"a.b.c", false, false, false, true},
+    }
+    for _, test := range tests {
+        if has := conf.HasPath(test.path); has != test.has {
+            t.Errorf("HasPath(%q) = %v", test.path, has)
+        }
+        if has := conf.HasPathOrNull(test.path); has != test.orNull {
+            t.Errorf("HasPathOrNull(%q) = %v", test.path, has)
+        }
+        null, err := conf.IsNull(test.path)
+        if null != test.null || (err != nil) != test.nullErr {
+            t.Errorf("IsNull(%q) = %v, %v", test.path, null, err)
+        }
+    }
+    for path, empty := range map[string]bool{"a": false, "a.b": false, "a.empty": true, "a.list": true} {
+        v, err := conf.GetValue(path)
+        if err != nil {
+            t.Fatal(err)
+        }
+        if v.IsEmpty() != empty {
+            t.Errorf("%s: IsEmpty() = %v", path, !empty)
+        }
+    }
+}
+
+const listInput = `
+lists {
+    strings = ["a", "b c", "d"]
+    ints = [1, -2, 3]
+    uints = [1, 2, 3]
+    floats = [1.5, 2, 3e2]
+    bools = [true, off, on]
+    durations = ["1 s", 500, "2m", "1.5 hours"]
+    bytes = ["512b", 1024, "1 KiB", "10MB", "1.5 G"]
+    objects = [{a = 1}, {a = 2}]
+    mixed = [1, "two", 3]
+}
+`
+
+func TestListGetters(t *testing.T) {
+    conf := testConfig(t, listInput)
+    check := func(name string, got interface{}, err error, expected string) {
+        if err != nil {
+            t.Errorf("%s: unexpected error: %v", name, err)
+        } else if s := fmt.Sprint(got); s != expected {
+            t.Errorf("%s: got %s, expected %s", name, s, expected)
+        }
+    }
+    strs, err := conf.GetStringList("lists.strings")
+    check("strings", strs, err, "[a b c d]")
+    ints, err := conf.GetIntList("lists.ints")
+    check("ints", ints, err, "[1 -2 3]")
+    uints, err := conf.GetUIntList("lists.uints")
+    check("uints", uints, err, "[1 2 3]")
+    floats, err := conf.GetFloatList("lists.floats")
+    check("floats", floats, err, "[1.5 2 300]")
+    bools, err := conf.GetBoolList("lists.bools")
+    check("bools", bools, err, "[true false true]")
+    durations, err := conf.GetDurationList("lists.durations")
+    check("durations", durations, err, "[1s 500ms 2m0s 1h30m0s]")
+    bytes, err := conf.GetBytesList("lists.bytes")
+    check("bytes", bytes, err, "[512 1024 1024 10000000 1610612736]")
+    objects, err := conf.GetConfigList("lists.objects")
+    check("objects", objects, err, "[a = (1) a = (2)]")
+
+    if _, err := conf.GetIntList("lists.mixed"); err == nil || err.Error() != "not valid int64: lists.mixed.1" {
+        t.Errorf("mixed: got error %v", err)
+    }
+    if _, err := testConfig(t, "l = [1, 2.5]").GetIntList("l"); err == nil || err.Error() != "not valid int64: l.1" {
+        t.Errorf("float in an int list: got error %v", err)
+    }
+    if _, err := testConfig(t, "l = [1, -2]").GetUIntList("l"); err == nil || err.Error() != "not valid uint64: l.1" {
+        t.Errorf("negative in a uint list: got error %v", err)
+    }
+    if _, err := conf.GetConfigList("lists.ints"); err == nil || err.Error() != "not valid object: lists.ints.0" {
+        t.Errorf("objects: got error %v", err)
+    }
+    if _, err := conf.GetStringList("lists"); err == nil {
+        t.Errorf("expected error for a non-list")
+    }
+    if _, err := conf.GetStringList("lists.missing"); err == nil {
+        t.Errorf("expected error for a missing list")
+    }
+}
+
+func TestUnits(t *testing.T) {
+    conf := testConfig(t, `
+        timeout = 100 s
+        tick = 9 ms
+        plain = 250
+        buffer = 30720000b
+        frame = 10 MiB
+        bad = 10 parsecs
+    `)
+    if d, err := conf.GetDuration("timeout"); err != nil || d != 100*time.Second {
+        t.Errorf("timeout: got %v, %v", d, err)
+    }
+    if d, err := conf.GetDuration("tick"); err != nil || d != 9*time.Millisecond {
+        t.Errorf("tick: got %v, %v", d, err)
+    }
+    if d, err := conf.GetDuration("plain"); err != nil || d != 250*time.Millisecond {
+        t.Errorf("plain: got %v, %v", d, err)
+    }
+    if b, err := conf.GetBytes("buffer"); err != nil || b != 30720000 {
+        t.Errorf("buffer: got %v, %v", b, err)
+    }
+    if b, err := conf.GetBytes("frame"); err != nil || b != 10<<20 {
+        t.Errorf("frame: got %v, %v", b, err)
+    }
+    if _, err := conf.GetDuration("bad"); err == nil {
+        t.Errorf("bad: expected error")
+    }
+    if _, err := conf.GetBytes("bad"); err == nil {
+        t.Errorf("bad: expected error")
+    }
+}
+
+func TestDefaults(t *testing.T) {
+    conf := testConfig(t, `
+        a { port = 8080, name = web, n = null, list = [1, 2] }
+    `)
+    if v, err := conf.GetIntOr("a.port", 10); err != nil || v != 8080 {
+        t.Errorf("present: got %v, %v", v, err)
+    }
+    if v, err := conf.GetIntOr("a.missing", 10); err != nil || v != 10 {
+        t.Errorf("missing: got %v, %v", v, err)
+    }
+    if v, err := conf.GetIntOr("a.n", 10); err != nil || v != 10 {
+        t.Errorf("null: got %v, %v", v, err)
+    }
+    if _, err := conf.GetIntOr("a.name", 10); err == nil {
+        t.Errorf("wrong type: expected an error")
+    }
+    if _, err := conf.GetIntOr("a.name.port", 10); err == nil {
+        t.Errorf("path through a scalar: expected an error")
+    }
+    if v, err := conf.GetIntListOr("a.other", []int64{3}); err != nil || len(v) != 1 || v[0] != 3 {
+        t.Errorf("list: got %v, %v", v, err)
+    }
+    if v := conf.MustGetString("a.name"); v != "web" {
+        t.Errorf("MustGetString: got %q", v)
+    }
+    defer func() {
+        if recover() == nil {
+            t.Errorf("MustGetInt: expected a panic")
+        }
+    }()
+    conf.MustGetInt("a.name")
+}
+
+func TestConversions(t *testing.T) {
+    conf := testConfig(t, `
+        port = 8080
+        ratio = 8.0
+        enabled = true
+        answer = "42"
+        fraction = "0.25"
+        yes = "yes"
+        off = "off"
+        word = "maybe"
+        big = 5000000000
+        huge = 1e300
+    `)
+    if v, err := conf.GetString("port"); err != nil || v != "8080" {
+        t.Errorf("number as string: got %q, %v", v, err)
+    }
+    if v, err := conf.GetString("enabled"); err != nil || v != "true" {
+        t.Errorf("bool as string: got %q, %v", v, err)
+    }
+    if v, err := conf.GetInt("ratio"); err != nil || v != 8 {
+        t.Errorf("integral float as int: got %v, %v", v, err)
+    }
+    if v, err := conf.GetInt("answer"); err != nil || v != 42 {
+        t.Errorf("string as int: got %v, %v", v, err)
+    }
+    if v, err := conf.GetFloat("fraction"); err != nil || v != 0.25 {
+        t.Errorf("string as float: got %v, %v", v, err)
+    }
+    if v, err := conf.GetBool("yes"); err != nil || !v {
+        t.Errorf("yes as bool: got %v, %v", v, err)
+    }
+    if v, err := conf.GetBool("off"); err != nil || v {
+        t.Errorf("off as bool: got %v, %v", v, err)
+    }
+    if _, err := conf.GetBool("word"); err == nil {
+        t.Errorf("maybe as bool: expected an error")
+    }
+    if _, err := conf.GetInt("word"); err == nil {
+        t.Errorf("maybe as int: expected an error")
+    }
+
+    strict := conf.WithStrictTypes(true)
+    if _, err := strict.GetString("port"); err == nil {
+        t.Errorf("strict number as string: expected an error")
+    }
+    if _, err := strict.GetInt("answer"); err == nil {
+        t.Errorf("strict string as int: expected an error")
+    }
+    if _, err := strict.GetBool("yes"); err == nil {
+        t.Errorf("strict string as bool: expected an error")
+    }
+    if v, err := strict.GetFloat("port"); err != nil || v != 8080 {
+        t.Errorf("strict int as float: got %v, %v", v, err)
+    }
+    if sub, _ := strict.GetValue(""); sub.strict != true {
+        t.Errorf("strictness not inherited by GetValue")
+    }
+
+    if v, err := conf.GetInt32("port"); err != nil || v != 8080 {
+        t.Errorf("int32: got %v, %v", v, err)
+    }
+    if _, err := conf.GetInt32("big"); err == nil {
+        t.Errorf("int32 overflow: expected an error")
+    }
+    if v, err := conf.GetUint32("port"); err != nil || v != 8080 {
+        t.Errorf("uint32: got %v, %v", v, err)
+    }
+    if _, err := conf.GetUint32("big"); err == nil {
+        t.Errorf("uint32 overflow: expected an error")
+    }
+    if v, err := conf.GetFloat32("fraction"); err != nil || v != 0.25 {
+        t.Errorf("float32: got %v, %v", v, err)
+    }
+    if _, err := conf.GetFloat32("huge"); err == nil {
+        t.Errorf("float32 overflow: expected an error")
+    }
+}
+
+func TestBigNumbers(t *testing.T) {
+    conf := testConfig(t, `
+        id = 123456789012345678901234567890
+        price = 0.1
+        hex = 0x1234567890abcdef1234
+        small = 42
+        text = "98765432109876543210"
+        vast = 1e400
+    `)
+    id, err := conf.GetBigInt("id")
+    if err != nil || id.String() != "123456789012345678901234567890" {
+        t.Errorf("id: got %v, %v", id, err)
+    }
+    if _, err := conf.GetInt("id"); err == nil {
+        t.Errorf("id: expected int64 overflow")
+    }
+    if f, err := conf.GetFloat("id"); err != nil || f != 1.2345678901234568e29 {
+        t.Errorf("id as float: got %v, %v", f, err)
+    }
+    hex, err := conf.GetBigInt("hex")
+    if err != nil || hex.Text(16) != "1234567890abcdef1234" {
+        t.Errorf("hex: got %v, %v", hex, err)
+    }
+    price, err := conf.GetBigFloat("price")
+    if err != nil || price.Text('g', 10) != "0.1" {
+        t.Errorf("price: got %v, %v", price, err)
+    }
+    if _, err := conf.GetBigInt("price"); err == nil {
+        t.Errorf("price: expected not an integer")
+    }
+    vast, err := conf.GetBigFloat("vast")
+    if err != nil || vast.Text('g', 10) != "1e+400" {
+        t.Errorf("vast: got %v, %v", vast, err)
+    }
+    if _, err := conf.GetFloat("vast"); err == nil || err.Error() != "float64 out of range: vast" {
+        t.Errorf("vast as float: got %v", err)
+    }
+    if s, err := conf.GetNumberText("price"); err != nil || s != "0.1" {
+        t.Errorf("price text: got %q, %v", s, err)
+    }
+    if i, err := conf.GetBigInt("text"); err != nil || i.String() != "98765432109876543210" {
+        t.Errorf("numeric string: got %v, %v", i, err)
+    }
+    small, _ := conf.GetBigInt("small")
+    small.SetInt64(0)
+    if again, _ := conf.GetBigInt("small"); again.Int64() != 42 {
+        t.Errorf("GetBigInt returned a shared value")
+    }
+    v, _ := conf.GetValue("id")
+    if v.String() != "123456789012345678901234567890" {
+        t.Errorf("id text: got %s", v)
+    }
+}
+
+func TestConcatenationValue(t *testing.T) {
+    conf := testConfig(t, `
+        a = "foo"   bar  "  baz"
+        b = 1 2
+        c = akka.tcp"://"ripak"@"127.0.0.1":"2554
+    `)
+    for path, expected := range map[string]string{"a": "foo   bar    baz", "b": "1 2", "c": "akka.tcp://ripak@127.0.0.1:2554"} {
+        if v, err := conf.GetString(path); err != nil || v != expected {
+            t.Errorf("%s: got %q, %v, expected %q", path, v, err, expected)
+        }
+    }
+}
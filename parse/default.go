@@ -0,0 +1,381 @@
+package parse
+
+import (
+    "math/big"
+    "time"
+)
+
+// The GetXxxOr getters return def when path is missing or null, and
+// otherwise behave like GetXxx: a value of the wrong type is still an
+// error. The MustGetXxx getters panic instead of returning an error, for
+// use in initialization code.
+
+// missing reports whether path is absent or null.
+func (c *Config) missing(path string) bool {
+    conf, err := c.GetValue(path)
+    if err != nil {
+        perr, ok := err.(*PathError)
+        return ok && perr.Err == ErrMissing
+    }
+    return conf.root.Type() == NodeNil
+}
+
+func (c *Config) GetValueOr(path string, def *Config) (*Config, error) {
+    if c.missing(path) {
+        return def, nil
+    }
+    return c.GetValue(path)
+}
+
+func (c *Config) MustGetValue(path string) *Config {
+    val, err := c.GetValue(path)
+    if err != nil {
+        panic(err)
+    }
+    return val
+}
+
+func (c *Config) GetStringOr(path string, def string) (string, error) {
+    if c.missing(path) {
+        return def, nil
+    }
+    return c.GetString(path)
+}
+
+func (c *Config) MustGetString(path string) string {
+    val, err := c.GetString(path)
+    if err != nil {
+        panic(err)
+    }
+    return val
+}
+
+func (c *Config) GetBoolOr(path string, def bool) (bool, error) {
+    if c.missing(path) {
+        return def, nil
+    }
+    return c.GetBool(path)
+}
+
+func (c *Config) MustGetBool(path string) bool {
+    val, err := c.GetBool(path)
+    if err != nil {
+        panic(err)
+    }
+    return val
+}
+
+func (c *Config) GetIntOr(path string, def int64) (int64, error) {
+    if c.missing(path) {
+        return def, nil
+    }
+    return c.GetInt(path)
+}
+
+func (c *Config) MustGetInt(path string) int64 {
+    val, err := c.GetInt(path)
+    if err != nil {
+        panic(err)
+    }
+    return val
+}
+
+func (c *Config) GetUIntOr(path string, def uint64) (uint64, error) {
+    if c.missing(path) {
+        return def, nil
+    }
+    return c.GetUInt(path)
+}
+
+func (c *Config) MustGetUInt(path string) uint64 {
+    val, err := c.GetUInt(path)
+    if err != nil {
+        panic(err)
+    }
+    return val
+}
+
+func (c *Config) GetFloatOr(path string, def float64) (float64, error) {
+    if c.missing(path) {
+        return def, nil
+    }
+    return c.GetFloat(path)
+}
+
+func (c *Config) MustGetFloat(path string) float64 {
+    val, err := c.GetFloat(path)
+    if err != nil {
+        panic(err)
+    }
+    return val
+}
+
+func (c *Config) GetInt32Or(path string, def int32) (int32, error) {
+    if c.missing(path) {
+        return def, nil
+    }
+    return c.GetInt32(path)
+}
+
+func (c *Config) MustGetInt32(path string) int32 {
+    val, err := c.GetInt32(path)
+    if err != nil {
+        panic(err)
+    }
+    return val
+}
+
+func (c *Config) GetUint32Or(path string, def uint32) (uint32, error) {
+    if c.missing(path) {
+        return def, nil
+    }
+    return c.GetUint32(path)
+}
+
+func (c *Config) MustGetUint32(path string) uint32 {
+    val, err := c.GetUint32(path)
+    if err != nil {
+        panic(err)
+    }
+    return val
+}
+
+func (c *Config) GetFloat32Or(path string, def float32) (float32, error) {
+    if c.missing(path) {
+        return def, nil
+    }
+    return c.GetFloat32(path)
+}
+
+func (c *Config) MustGetFloat32(path string) float32 {
+    val, err := c.GetFloat32(path)
+    if err != nil {
+        panic(err)
+    }
+    return val
+}
+
+func (c *Config) GetComplexOr(path string, def complex128) (complex128, error) {
+    if c.missing(path) {
+        return def, nil
+    }
+    return c.GetComplex(path)
+}
+
+func (c *Config) MustGetComplex(path string) complex128 {
+    val, err := c.GetComplex(path)
+    if err != nil {
+        panic(err)
+    }
+    return val
+}
+
+func (c *Config) GetBigIntOr(path string, def *big.Int) (*big.Int, error) {
+    if c.missing(path) {
+        return def, nil
+    }
+    return c.GetBigInt(path)
+}
+
+func (c *Config) MustGetBigInt(path string) *big.Int {
+    val, err := c.GetBigInt(path)
+    if err != nil {
+        panic(err)
+    }
+    return val
+}
+
+func (c *Config) GetBigFloatOr(path string, def *big.Float) (*big.Float, error) {
+    if c.missing(path) {
+        return def, nil
+    }
+    return c.GetBigFloat(path)
+}
+
+func (c *Config) MustGetBigFloat(path string) *big.Float {
+    val, err := c.GetBigFloat(path)
+    if err != nil {
+        panic(err)
+    }
+    return val
+}
+
+func (c *Config) GetNumberTextOr(path string, def string) (string, error) {
+    if c.missing(path) {
+        return def, nil
+    }
+    return c.GetNumberText(path)
+}
+
+func (c *Config) MustGetNumberText(path string) string {
+    val, err := c.GetNumberText(path)
+    if err != nil {
+        panic(err)
+    }
+    return val
+}
+
+func (c *Config) GetDurationOr(path string, def time.Duration) (time.Duration, error) {
+    if c.missing(path) {
+        return def, nil
+    }
+    return c.GetDuration(path)
+}
+
+func (c *Config) MustGetDuration(path string) time.Duration {
+    val, err := c.GetDuration(path)
+    if err != nil {
+        panic(err)
+    }
+    return val
+}
+
+func (c *Config) GetBytesOr(path string, def int64) (int64, error) {
+    if c.missing(path) {
+        return def, nil
+    }
+    return c.GetBytes(path)
+}
+
+func (c *Config) MustGetBytes(path string) int64 {
+    val, err := c.GetBytes(path)
+    if err != nil {
+        panic(err)
+    }
+    return val
+}
+
+func (c *Config) GetArrayOr(path string, def []*Config) ([]*Config, error) {
+    if c.missing(path) {
+        return def, nil
+    }
+    return c.GetArray(path)
+}
+
+func (c *Config) MustGetArray(path string) []*Config {
+    val, err := c.GetArray(path)
+    if err != nil {
+        panic(err)
+    }
+    return val
+}
+
+func (c *Config) GetStringListOr(path string, def []string) ([]string, error) {
+    if c.missing(path) {
+        return def, nil
+    }
+    return c.GetStringList(path)
+}
+
+func (c *Config) MustGetStringList(path string) []string {
+    val, err := c.GetStringList(path)
+    if err != nil {
+        panic(err)
+    }
+    return val
+}
+
+func (c *Config) GetBoolListOr(path string, def []bool) ([]bool, error) {
+    if c.missing(path) {
+        return def, nil
+    }
+    return c.GetBoolList(path)
+}
+
+func (c *Config) MustGetBoolList(path string) []bool {
+    val, err := c.GetBoolList(path)
+    if err != nil {
+        panic(err)
+    }
+    return val
+}
+
+func (c *Config) GetIntListOr(path string, def []int64) ([]int64, error) {
+    if c.missing(path) {
+        return def, nil
+    }
+    return c.GetIntList(path)
+}
+
+func (c *Config) MustGetIntList(path string) []int64 {
+    val, err := c.GetIntList(path)
+    if err != nil {
+        panic(err)
+    }
+    return val
+}
+
+func (c *Config) GetUIntListOr(path string, def []uint64) ([]uint64, error) {
+    if c.missing(path) {
+        return def, nil
+    }
+    return c.GetUIntList(path)
+}
+
+func (c *Config) MustGetUIntList(path string) []uint64 {
+    val, err := c.GetUIntList(path)
+    if err != nil {
+        panic(err)
+    }
+    return val
+}
+
+func (c *Config) GetFloatListOr(path string, def []float64) ([]float64, error) {
+    if c.missing(path) {
+        return def, nil
+    }
+    return c.GetFloatList(path)
+}
+
+func (c *Config) MustGetFloatList(path string) []float64 {
+    val, err := c.GetFloatList(path)
+    if err != nil {
+        panic(err)
+    }
+    return val
+}
+
+func (c *Config) GetDurationListOr(path string, def []time.Duration) ([]time.Duration, error) {
+    if c.missing(path) {
+        return def, nil
+    }
+    return c.GetDurationList(path)
+}
+
+func (c *Config) MustGetDurationList(path string) []time.Duration {
+    val, err := c.GetDurationList(path)
+    if err != nil {
+        panic(err)
+    }
+    return val
+}
+
+func (c *Config) GetBytesListOr(path string, def []int64) ([]int64, error) {
+    if c.missing(path) {
+        return def, nil
+    }
+    return c.GetBytesList(path)
+}
+
+func (c *Config) MustGetBytesList(path string) []int64 {
+    val, err := c.GetBytesList(path)
+    if err != nil {
+        panic(err)
+    }
+    return val
+}
+
+func (c *Config) GetConfigListOr(path string, def []*Config) ([]*Config, error) {
+    if c.missing(path) {
+        return def, nil
+    }
+    return c.GetConfigList(path)
+}
+
+func (c *Config) MustGetConfigList(path string) []*Config {
+    val, err := c.GetConfigList(path)
+    if err != nil {
+        panic(err)
+    }
+    return val
+}
@@ -0,0 +1,187 @@
+package parse
+
+import (
+    "bytes"
+    "crypto/sha256"
+    "encoding/hex"
+    "fmt"
+    "sort"
+    "strconv"
+)
+
+// Equal reports whether c and other hold the same values. Positions,
+// comments, quoting and the spelling of numbers are ignored, so that
+// {a: "x", b: 1.0} equals b = 1, a = x. A nil config equals only nil and
+// empty configs.
+func (c *Config) Equal(other *Config) bool {
+    return c.canonical() == other.canonical()
+}
+
+// Hash returns a hex-encoded SHA-256 digest of the values in c. Configs
+// that are Equal have the same hash, whatever the order of their keys.
+func (c *Config) Hash() string {
+    sum := sha256.Sum256([]byte(c.canonical()))
+    return hex.EncodeToString(sum[:])
+}
+
+// canonical returns the canonical text of the values in c, that of an
+// empty object if c is nil or has no root.
+func (c *Config) canonical() string {
+    if c == nil || c.root == nil {
+        return "{}"
+    }
+    return canonical(c.root)
+}
+
+// canonical returns a text identifying the value of n, independent of its
+// formatting.
+func canonical(n Node) string {
+    var b bytes.Buffer
+    writeCanonical(&b, n)
+    return b.String()
+}
+
+func writeCanonical(b *bytes.Buffer, n Node) {
+    switch v := n.(type) {
+        case nil:
+        case *MapNode:
+            b.WriteByte('{')
+            for _, k := range v.sortedKeys() {
+                b.WriteString(strconv.Quote(k))
+                b.WriteByte(':')
+                writeCanonical(b, v.Nodes[k])
+                b.WriteByte(',')
+            }
+            b.WriteByte('}')
+        case *ListNode:
+            b.WriteByte('[')
+            for _, elem := range v.Nodes {
+                writeCanonical(b, elem)
+                b.WriteByte(',')
+            }
+            b.WriteByte(']')
+        case *StringNode:
+            b.WriteString(strconv.Quote(v.Text))
+        case *TextNode:
+            b.WriteString(strconv.Quote(string(v.Text)))
+        case *NumberNode:
+            switch {
+                case v.IsComplex:
+                    fmt.Fprintf(b, "%v", v.Complex128)
+                case v.BigFloat != nil:
+                    b.WriteString(v.BigFloat.Text('g', -1))
+                default:
+                    b.WriteString(v.Text)
+            }
+        case *BoolNode:
+            fmt.Fprint(b, v.True)
+        case *ConcatNode:
+            b.WriteString("concat(")
+            for i, child := range v.Nodes {
+                b.WriteString(strconv.Quote(v.Spaces[i]))
+                writeCanonical(b, child)
+                b.WriteByte(',')
+            }
+            b.WriteByte(')')
+        case *SubstitutionNode:
+            b.WriteString("${")
+            if v.Optional {
+                b.WriteByte('?')
+            }
+            b.WriteString(v.Path)
+            b.WriteByte('}')
+        default:
+            // Null prints unambiguously.
+            b.WriteString(n.String())
+    }
+}
+
+// ChangeKind says how a path differs between two configs.
+type ChangeKind int
+
+const (
+    Added   ChangeKind = iota // The path is only in the new config.
+    Removed                   // The path is only in the old config.
+    Changed                   // The path has different values.
+)
+
+func (k ChangeKind) String() string {
+    switch k {
+        case Added:
+            return "added"
+        case Removed:
+            return "removed"
+        case Changed:
+            return "changed"
+    }
+    return fmt.Sprintf("ChangeKind(%d)", int(k))
+}
+
+// Change is a difference found by Diff. Old is nil for an added path and
+// New for a removed one; their Origin methods tell where each value was
+// defined.
+type Change struct {
+    Kind ChangeKind
+    Path Path
+    Old  *Config
+    New  *Config
+}
+
+func (c Change) String() string {
+    switch c.Kind {
+        case Added:
+            return fmt.Sprintf("+ %s = %s (%s)", c.Path, c.New, c.New.Origin())
+        case Removed:
+            return fmt.Sprintf("- %s = %s (%s)", c.Path, c.Old, c.Old.Origin())
+    }
+    old, new := c.Old.String(), c.New.String()
+    // A secret on one side only is still a secret on the other.
+    if hasSecret(c.Old.root) || hasSecret(c.New.root) {
+        old, new = Redacted, Redacted
+    }
+    return fmt.Sprintf("~ %s = %s (%s) -> %s (%s)", c.Path, old, c.Old.Origin(), new, c.New.Origin())
+}
+
+// Diff returns the differences between a and b, sorted by path. Objects
+// are compared key by key; any other values, lists included, are compared
+// as a whole with Equal. An object added or removed is a single change.
+func Diff(a, b *Config) []Change {
+    var changes []Change
+    diff(&changes, Path{}, a, b)
+    return changes
+}
+
+func diff(changes *[]Change, path Path, a, b *Config) {
+    am, aok := a.root.(*MapNode)
+    bm, bok := b.root.(*MapNode)
+    if !aok || !bok {
+        if !a.Equal(b) {
+            *changes = append(*changes, Change{Changed, path, a, b})
+        }
+        return
+    }
+    for _, k := range unionKeys(am, bm) {
+        an, inA := am.Nodes[k]
+        bn, inB := bm.Nodes[k]
+        switch {
+            case !inA:
+                *changes = append(*changes, Change{Added, path.child(k), nil, b.wrap(bn)})
+            case !inB:
+                *changes = append(*changes, Change{Removed, path.child(k), a.wrap(an), nil})
+            default:
+                diff(changes, path.child(k), a.wrap(an), b.wrap(bn))
+        }
+    }
+}
+
+// unionKeys returns the keys of a and b, sorted.
+func unionKeys(a, b *MapNode) []string {
+    keys := a.sortedKeys()
+    for k := range b.Nodes {
+        if _, ok := a.Nodes[k]; !ok {
+            keys = append(keys, k)
+        }
+    }
+    sort.Strings(keys)
+    return keys
+}
@@ -0,0 +1,84 @@
+package parse
+
+import (
+    "strings"
+    "testing"
+)
+
+type equalTest struct {
+    a, b  string
+    equal bool
+}
+
+var equalTests = []equalTest{
+    {`a = 1, b = x`, `b: "x"
+                      a: 1`, true},
+    {`a { b = 1.0 }`, `a.b = 1`, true},
+    {`a = 1e3`, `a = 1000`, true},
+    {`a = 1`, `a = "1"`, false},
+    {`a = true`, `a = "true"`, false},
+    {`a = [1, 2]`, `a = [2, 1]`, false},
+    {`a = null`, `a = null`, true},
+    {`a = ${b}`, `a = ${ b }`, true},
+    {`a = ${b}`, `a = ${?b}`, false},
+    {`a = {}`, `a = []`, false},
+    {`a = 1`, `a = 1, b = 2`, false},
+}
+
+func TestEqualAndHash(t *testing.T) {
+    for _, test := range equalTests {
+        a, b := testConfig(t, test.a), testConfig(t, test.b)
+        if a.Equal(b) != test.equal {
+            t.Errorf("%q and %q: got equal %v, expected %v", test.a, test.b, !test.equal, test.equal)
+        }
+        if (a.Hash() == b.Hash()) != test.equal {
+            t.Errorf("%q and %q: hashes %s and %s", test.a, test.b, a.Hash(), b.Hash())
+        }
+    }
+}
+
+func TestEqualNil(t *testing.T) {
+    var none *Config
+    if !none.Equal(nil) || !none.Equal(testConfig(t, "")) || !testConfig(t, "").Equal(none) {
+        t.Error("a nil config is not equal to nil or an empty config")
+    }
+    if none.Equal(testConfig(t, "a = 1")) || testConfig(t, "a = 1").Equal(none) {
+        t.Error("a nil config is equal to a config with values")
+    }
+    if none.Hash() != testConfig(t, "").Hash() {
+        t.Error("a nil and an empty config have different hashes")
+    }
+}
+
+func TestDiff(t *testing.T) {
+    before, err := Parse("old.conf", `a { b = 1, c = 2 }
+d = [1, 2]
+e = x
+f = { g = 1 }`)
+    if err != nil {
+        t.Fatal(err)
+    }
+    after, err := Parse("new.conf", `a { b = 1, c = 3 }
+d = [1, 2, 3]
+e = x
+h = y`)
+    if err != nil {
+        t.Fatal(err)
+    }
+    var got []string
+    for _, c := range Diff(before.GetConfig(), after.GetConfig()) {
+        got = append(got, c.String())
+    }
+    expect := []string{
+        "~ a.c = 2 (old.conf:1:16) -> 3 (new.conf:1:16)",
+        "~ d = 12 (old.conf:2:5) -> 123 (new.conf:2:5)",
+        "- f = g = (1) (old.conf:4:5)",
+        "+ h = y (new.conf:4:5)",
+    }
+    if strings.Join(got, "\n") != strings.Join(expect, "\n") {
+        t.Errorf("got\n\t%s\nexpected\n\t%s", strings.Join(got, "\n\t"), strings.Join(expect, "\n\t"))
+    }
+    if changes := Diff(before.GetConfig(), before.GetConfig()); len(changes) != 0 {
+        t.Errorf("config differs from itself: %v", changes)
+    }
+}
@@ -0,0 +1,335 @@
+package parse
+
+import (
+    "bufio"
+    "bytes"
+    "crypto/aes"
+    "crypto/cipher"
+    "crypto/rand"
+    "encoding/base64"
+    "fmt"
+    "io/ioutil"
+    "os"
+    "strconv"
+    "strings"
+)
+
+// Encrypted values are strings of the form
+//
+//	"ENC[AES256_GCM,key:<id>,data:<base64>,iv:<base64>]"
+//
+// holding a value encrypted with AES-256 in GCM mode by the key of a
+// Keyring named id, and bound to the path of the value: moved to another
+// path, it no longer decrypts. Being strings, they must be quoted in
+// HOCON. Resolve decrypts them with the keyring of ResolveOptions, or
+// else the one in the environment, and marks the values as secret.
+const (
+    encPrefix = "ENC[AES256_GCM,"
+    encSuffix = "]"
+)
+
+// The environment variables read by KeyringFromEnv.
+const (
+    KeyfileEnv = "CONFIG_KEYFILE" // the name of a keyring file
+    KeysEnv    = "CONFIG_KEYS"    // the text of a keyring
+)
+
+// KeySize is the size in bytes of the keys of a Keyring.
+const KeySize = 32
+
+// Keyring holds the keys that encrypt and decrypt config values, by name.
+// The first key is the one that encrypts.
+type Keyring struct {
+    keys    map[string][]byte
+    primary string
+}
+
+// NewKeyring returns an empty keyring.
+func NewKeyring() *Keyring {
+    return &Keyring{keys: make(map[string][]byte)}
+}
+
+// Add adds key, which must be KeySize bytes long, under the name id.
+func (k *Keyring) Add(id string, key []byte) error {
+    if id == "" || strings.ContainsAny(id, ",:] \t\n") {
+        return fmt.Errorf("bad key name %q", id)
+    }
+    if len(key) != KeySize {
+        return fmt.Errorf("key %s: %d bytes, expected %d", id, len(key), KeySize)
+    }
+    if _, ok := k.keys[id]; ok {
+        return fmt.Errorf("key %s: defined twice", id)
+    }
+    if len(k.keys) == 0 {
+        k.primary = id
+    }
+    k.keys[id] = append([]byte{}, key...)
+    return nil
+}
+
+// GenerateKey returns a new random key.
+func GenerateKey() ([]byte, error) {
+    key := make([]byte, KeySize)
+    if _, err := rand.Read(key); err != nil {
+        return nil, err
+    }
+    return key, nil
+}
+
+// ParseKeyring reads a keyring from text: one key per line, or per comma,
+// as its name, a colon and the key in base64. Blank lines and lines
+// starting with # are ignored.
+func ParseKeyring(text string) (*Keyring, error) {
+    k := NewKeyring()
+    scanner := bufio.NewScanner(strings.NewReader(text))
+    for line := 1; scanner.Scan(); line++ {
+        l := strings.TrimSpace(scanner.Text())
+        if l == "" || strings.HasPrefix(l, "#") {
+            continue
+        }
+        for _, entry := range strings.Split(l, ",") {
+            entry = strings.TrimSpace(entry)
+            i := strings.Index(entry, ":")
+            if i < 0 {
+                return nil, fmt.Errorf("keyring line %d: expected name:key", line)
+            }
+            key, err := base64.StdEncoding.DecodeString(entry[i+1:])
+            if err != nil {
+                return nil, fmt.Errorf("keyring line %d: %v", line, err)
+            }
+            if err := k.Add(entry[:i], key); err != nil {
+                return nil, fmt.Errorf("keyring line %d: %v", line, err)
+            }
+        }
+    }
+    if len(k.keys) == 0 {
+        return nil, fmt.Errorf("keyring has no keys")
+    }
+    return k, nil
+}
+
+// LoadKeyring reads the keyring file name, as ParseKeyring does.
+func LoadKeyring(name string) (*Keyring, error) {
+    text, err := ioutil.ReadFile(name)
+    if err != nil {
+        return nil, err
+    }
+    k, err := ParseKeyring(string(text))
+    if err != nil {
+        return nil, fmt.Errorf("%s: %v", name, err)
+    }
+    return k, nil
+}
+
+// KeyringFromEnv returns the keyring given by the environment: the text
+// of KeysEnv, or else the file named by KeyfileEnv. It returns nil if
+// neither is set.
+func KeyringFromEnv() (*Keyring, error) {
+    if text := os.Getenv(KeysEnv); text != "" {
+        k, err := ParseKeyring(text)
+        if err != nil {
+            return nil, fmt.Errorf("%s: %v", KeysEnv, err)
+        }
+        return k, nil
+    }
+    if name := os.Getenv(KeyfileEnv); name != "" {
+        return LoadKeyring(name)
+    }
+    return nil, nil
+}
+
+// IsEncrypted reports whether s is an encrypted value.
+func IsEncrypted(s string) bool {
+    return strings.HasPrefix(s, encPrefix) && strings.HasSuffix(s, encSuffix)
+}
+
+// Encrypt encrypts value, to be set at path, with the first key of k.
+func (k *Keyring) Encrypt(path, value string) (string, error) {
+    if k.primary == "" {
+        return "", fmt.Errorf("keyring has no keys")
+    }
+    aead, err := newAEAD(k.keys[k.primary])
+    if err != nil {
+        return "", err
+    }
+    iv := make([]byte, aead.NonceSize())
+    if _, err := rand.Read(iv); err != nil {
+        return "", err
+    }
+    data := aead.Seal(nil, iv, []byte(value), []byte(canonicalPath(path)))
+    return fmt.Sprintf("%skey:%s,data:%s,iv:%s%s", encPrefix, k.primary,
+        base64.StdEncoding.EncodeToString(data), base64.StdEncoding.EncodeToString(iv), encSuffix), nil
+}
+
+// Decrypt decrypts the encrypted value s, set at path, with the key it
+// names.
+func (k *Keyring) Decrypt(path, s string) (string, error) {
+    if !IsEncrypted(s) {
+        return "", fmt.Errorf("not an encrypted value")
+    }
+    fields := make(map[string]string)
+    for _, f := range strings.Split(s[len(encPrefix):len(s)-len(encSuffix)], ",") {
+        i := strings.Index(f, ":")
+        if i < 0 {
+            return "", fmt.Errorf("malformed encrypted value")
+        }
+        fields[f[:i]] = f[i+1:]
+    }
+    key, ok := k.keys[fields["key"]]
+    if !ok {
+        return "", fmt.Errorf("no key %q to decrypt with", fields["key"])
+    }
+    data, err := base64.StdEncoding.DecodeString(fields["data"])
+    if err != nil {
+        return "", fmt.Errorf("malformed encrypted value: %v", err)
+    }
+    iv, err := base64.StdEncoding.DecodeString(fields["iv"])
+    if err != nil {
+        return "", fmt.Errorf("malformed encrypted value: %v", err)
+    }
+    aead, err := newAEAD(key)
+    if err != nil {
+        return "", err
+    }
+    if len(iv) != aead.NonceSize() {
+        return "", fmt.Errorf("malformed encrypted value: bad iv")
+    }
+    value, err := aead.Open(nil, iv, data, []byte(canonicalPath(path)))
+    if err != nil {
+        return "", fmt.Errorf("cannot decrypt with key %s at %s: %v", fields["key"], path, err)
+    }
+    return string(value), nil
+}
+
+// canonicalPath returns path as Path.String writes it, so that the
+// spellings of a path, such as a."b" and a.b, encrypt alike.
+func canonicalPath(path string) string {
+    if segs, err := parsePath(path); err == nil {
+        return Path(segs).String()
+    }
+    return path
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+    block, err := aes.NewCipher(key)
+    if err != nil {
+        return nil, err
+    }
+    return cipher.NewGCM(block)
+}
+
+// decrypt returns the decrypted value of the encrypted string s.
+func (r *resolver) decrypt(s *StringNode) (Node, error) {
+    if r.keyring == nil {
+        k, err := KeyringFromEnv()
+        if err != nil {
+            return nil, fmt.Errorf("%s: %v", location(s), err)
+        }
+        if k == nil {
+            return nil, fmt.Errorf("%s: no keys to decrypt the value with: set %s or %s", location(s), KeysEnv, KeyfileEnv)
+        }
+        r.keyring = k
+    }
+    value, err := r.keyring.Decrypt(r.encrypted[s].String(), s.Text)
+    if err != nil {
+        return nil, fmt.Errorf("%s: %v", location(s), err)
+    }
+    n := s.tr.newString(s.Pos, quote(value), value)
+    n.Secret = true
+    return n, nil
+}
+
+// encryptedPaths records in paths where the encrypted strings of n are,
+// n being at path.
+func encryptedPaths(n Node, path Path, paths map[*StringNode]Path) {
+    switch v := n.(type) {
+        case *MapNode:
+            for k, child := range v.Nodes {
+                encryptedPaths(child, path.child(k), paths)
+            }
+        case *ListNode:
+            for i, child := range v.Nodes {
+                encryptedPaths(child, path.child(strconv.Itoa(i)), paths)
+            }
+        case *ConcatNode:
+            for _, child := range v.Nodes {
+                encryptedPaths(child, path, paths)
+            }
+        case *StringNode:
+            if IsEncrypted(v.Text) {
+                paths[v] = path
+            }
+    }
+}
+
+// EncryptSource returns the HOCON text src, of the file name, with the
+// value at path encrypted by k and the rest left as it is. The value must
+// be a string, number or boolean written in src; if it is written more
+// than once, the last one is encrypted.
+func EncryptSource(name string, src []byte, path string, k *Keyring) ([]byte, error) {
+    segs, err := parsePath(path)
+    if err != nil {
+        return nil, err
+    }
+    t := New(name)
+    t.noIncludes = true
+    if _, err := t.Parse(string(src)); err != nil {
+        return nil, err
+    }
+    want := Path(segs).String()
+    var field *Symbol
+    var find func(symbols []*Symbol)
+    find = func(symbols []*Symbol) {
+        for _, s := range symbols {
+            if s.Path.String() == want {
+                field = s
+            }
+            find(s.Children)
+        }
+    }
+    symbols, _ := Outline(name, string(src))
+    find(symbols)
+    if field == nil {
+        return nil, fmt.Errorf("%s: %s is not set in the file", name, path)
+    }
+    if field.Object {
+        return nil, fmt.Errorf("%s: %s is an object", name, path)
+    }
+
+    // The value follows the key and its separator.
+    start := int(field.KeyEnd)
+    for start < int(field.End) && strings.ContainsRune(" \t", rune(src[start])) {
+        start++
+    }
+    if start < int(field.End) && (src[start] == '=' || src[start] == ':') {
+        start++
+    }
+    for start < int(field.End) && strings.ContainsRune(" \t", rune(src[start])) {
+        start++
+    }
+    t, err = Parse(name, "v = "+string(src[start:field.End]))
+    if err != nil {
+        return nil, fmt.Errorf("%s: %s: cannot encrypt %s", name, path, src[field.KeyEnd:field.End])
+    }
+    conf := t.GetConfig()
+    value, _ := conf.GetValue("v")
+    switch n := value.root.(type) {
+        case *StringNode:
+            if IsEncrypted(n.Text) {
+                return nil, fmt.Errorf("%s: %s is already encrypted", name, path)
+            }
+        case *NumberNode, *BoolNode:
+        default:
+            return nil, fmt.Errorf("%s: %s is not a string, number or boolean", name, path)
+    }
+    text, _ := conf.GetString("v")
+    enc, err := k.Encrypt(want, text)
+    if err != nil {
+        return nil, err
+    }
+    var b bytes.Buffer
+    b.Write(src[:start])
+    b.WriteString(quote(enc))
+    b.Write(src[field.End:])
+    return b.Bytes(), nil
+}
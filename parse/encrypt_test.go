@@ -0,0 +1,124 @@
+package parse
+
+import (
+    "bytes"
+    "encoding/base64"
+    "os"
+    "strings"
+    "testing"
+)
+
+func testKeyring(t *testing.T, ids ...string) (*Keyring, string) {
+    k := NewKeyring()
+    var text []string
+    for _, id := range ids {
+        key, err := GenerateKey()
+        if err != nil {
+            t.Fatal(err)
+        }
+        if err := k.Add(id, key); err != nil {
+            t.Fatal(err)
+        }
+        text = append(text, id+":"+base64.StdEncoding.EncodeToString(key))
+    }
+    return k, strings.Join(text, "\n")
+}
+
+const encryptInput = `db {
+  user = app
+  # The password.
+  password = hunter2 // not for long
+  port = 5432
+}
+url = "pg://"${db.user}":"${db.password}"@db"
+`
+
+func TestEncryptSource(t *testing.T) {
+    k, keys := testKeyring(t, "k2", "k1")
+    out, err := EncryptSource("test", []byte(encryptInput), "db.password", k)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if out, err = EncryptSource("test", out, "db.port", k); err != nil {
+        t.Fatal(err)
+    }
+    lines := strings.Split(string(out), "\n")
+    if !strings.HasPrefix(lines[3], `  password = "ENC[AES256_GCM,key:k2,data:`) || !strings.HasSuffix(lines[3], `]" // not for long`) ||
+        bytes.Contains(out, []byte("hunter2")) || bytes.Contains(out, []byte("5432")) {
+        t.Errorf("got\n%s", out)
+    }
+    if strings.Join(append(lines[:3], lines[5:]...), "\n") != strings.Join(append(strings.Split(encryptInput, "\n")[:3], strings.Split(encryptInput, "\n")[5:]...), "\n") {
+        t.Errorf("other lines changed:\n%s", out)
+    }
+
+    // The keyring may come from the options or the environment.
+    tree, err := Parse("test", string(out))
+    if err != nil {
+        t.Fatal(err)
+    }
+    reordered, err := ParseKeyring(strings.Join([]string{strings.Split(keys, "\n")[1], strings.Split(keys, "\n")[0]}, ", "))
+    if err != nil {
+        t.Fatal(err)
+    }
+    conf, err := tree.GetConfig().ResolveWith(&ResolveOptions{Keyring: reordered})
+    if err != nil {
+        t.Fatal(err)
+    }
+    if s, _ := conf.GetString("url"); s != "pg://app:hunter2@db" || !conf.IsSecret("url") || !conf.IsSecret("db.password") {
+        t.Errorf("url: %q, secret %v", s, conf.IsSecret("url"))
+    }
+    if port, err := conf.GetInt("db.port"); err != nil || port != 5432 {
+        t.Errorf("port: %d, %v", port, err)
+    }
+    os.Setenv(KeysEnv, keys)
+    if _, err := tree.GetConfig().Resolve(); err != nil {
+        t.Errorf("keyring from the environment: %v", err)
+    }
+    os.Unsetenv(KeysEnv)
+    if _, err := tree.GetConfig().Resolve(); err == nil || !strings.Contains(err.Error(), "test:4:14: no keys") {
+        t.Errorf("without keys: got %v", err)
+    }
+    other, _ := testKeyring(t, "k2")
+    if _, err := tree.GetConfig().ResolveWith(&ResolveOptions{Keyring: other}); err == nil || !strings.Contains(err.Error(), "cannot decrypt with key k2") {
+        t.Errorf("with the wrong key: got %v", err)
+    }
+
+    // A value is bound to its path, however it is spelled.
+    line := strings.Split(string(out), "\n")[3]
+    blob := line[strings.Index(line, `"ENC[`) : strings.Index(line, `]"`)+2]
+    moved, err := Parse("test", `"db".password = `+blob+`, db.user = `+blob)
+    if err != nil {
+        t.Fatal(err)
+    }
+    _, err = moved.GetConfig().ResolveWith(&ResolveOptions{Keyring: k})
+    if err == nil || !strings.Contains(err.Error(), "cannot decrypt with key k2 at db.user: cipher: message authentication failed") {
+        t.Errorf("moved value: got %v", err)
+    }
+    if conf, err := testConfig(t, `"db".password = `+blob).ResolveWith(&ResolveOptions{Keyring: k}); err != nil {
+        t.Errorf("quoted path: %v", err)
+    } else if s, _ := conf.GetString("db.password"); s != "hunter2" {
+        t.Errorf("quoted path: got %q", s)
+    }
+
+    for _, path := range []string{"db", "url", "db.password", "nothing", "db..x"} {
+        if _, err := EncryptSource("test", out, path, k); err == nil {
+            t.Errorf("encrypting %s: expected an error", path)
+        }
+    }
+}
+
+func TestParseKeyringError(t *testing.T) {
+    for _, text := range []string{
+        "",
+        "# nothing",
+        "k1",
+        "k1:not base64!",
+        "k1:" + base64.StdEncoding.EncodeToString([]byte("short")),
+        "k:1:" + base64.StdEncoding.EncodeToString(make([]byte, KeySize)),
+        "k1:" + base64.StdEncoding.EncodeToString(make([]byte, KeySize)) + ",k1:" + base64.StdEncoding.EncodeToString(make([]byte, KeySize)),
+    } {
+        if _, err := ParseKeyring(text); err == nil {
+            t.Errorf("%q: expected an error", text)
+        }
+    }
+}
@@ -0,0 +1,117 @@
+package parse
+
+import (
+    "bytes"
+    "fmt"
+    "os"
+    "sort"
+    "strconv"
+    "strings"
+)
+
+// DefaultEnvPrefix is the prefix of the environment variables read by
+// EnvOverrides when it is given no other.
+const DefaultEnvPrefix = "CONFIG_FORCE_"
+
+// Environ returns the environment of the process as a map, for
+// EnvOverrides.
+func Environ() map[string]string {
+    env := make(map[string]string)
+    for _, kv := range os.Environ() {
+        if i := strings.Index(kv, "="); i > 0 {
+            env[kv[:i]] = kv[i+1:]
+        }
+    }
+    return env
+}
+
+// EnvOverrides returns a config with a value for every variable of env
+// whose name starts with prefix, or DefaultEnvPrefix if prefix is empty.
+// The rest of the name is the path of the value, with _ standing for a
+// dot, __ for a dash and ___ for an underscore, so that
+// CONFIG_FORCE_akka_remote__port sets akka.remote-port. Values are
+// strings, converted by the getters as usual. When a variable names an
+// object set by another one, the object wins.
+//
+// To apply the overrides, use them as the config with everything else as
+// their fallback: EnvOverrides(env, "").WithFallback(conf).
+func EnvOverrides(env map[string]string, prefix string) (*Config, error) {
+    if prefix == "" {
+        prefix = DefaultEnvPrefix
+    }
+    var names []string
+    for name := range env {
+        if strings.HasPrefix(name, prefix) {
+            names = append(names, name)
+        }
+    }
+    sort.Strings(names)
+
+    // The tree text lists the variables one per line, so that the origin
+    // of each value is its line.
+    var text bytes.Buffer
+    t := New("env")
+    t.ParseName = t.Name
+    root := t.newMap(0)
+    for _, name := range names {
+        pos := Pos(text.Len())
+        fmt.Fprintf(&text, "%s=%s\n", name, env[name])
+        segs, err := envPath(name[len(prefix):])
+        if err != nil {
+            return nil, fmt.Errorf("environment variable %s: %v", name, err)
+        }
+        m := root
+        for _, seg := range segs[:len(segs)-1] {
+            next, ok := m.Nodes[seg].(*MapNode)
+            if !ok {
+                next = t.newMap(pos)
+                m.Nodes[seg] = next
+            }
+            m = next
+        }
+        last := segs[len(segs)-1]
+        if _, ok := m.Nodes[last].(*MapNode); !ok {
+            m.Nodes[last] = t.newString(pos, strconv.Quote(env[name]), env[name])
+        }
+    }
+    t.text = text.String()
+    t.Root = root
+    return t.GetConfig(), nil
+}
+
+// envPath returns the path segments named by an environment variable
+// name without its prefix.
+func envPath(name string) ([]string, error) {
+    var segs []string
+    var seg bytes.Buffer
+    for i := 0; i < len(name); {
+        if name[i] != '_' {
+            seg.WriteByte(name[i])
+            i++
+            continue
+        }
+        n := 0
+        for i < len(name) && name[i] == '_' {
+            n++
+            i++
+        }
+        switch n {
+            case 1:
+                segs = append(segs, seg.String())
+                seg.Reset()
+            case 2:
+                seg.WriteByte('-')
+            case 3:
+                seg.WriteByte('_')
+            default:
+                return nil, fmt.Errorf("%d underscores in a row do not map to a path", n)
+        }
+    }
+    segs = append(segs, seg.String())
+    for _, s := range segs {
+        if s == "" {
+            return nil, fmt.Errorf("empty path segment in %q", name)
+        }
+    }
+    return segs, nil
+}
@@ -0,0 +1,67 @@
+package parse
+
+import (
+    "strings"
+    "testing"
+)
+
+func TestEnvPath(t *testing.T) {
+    tests := []struct {
+        name, path string
+        ok         bool
+    }{
+        {"akka_remote_port", "akka.remote.port", true},
+        {"akka_remote__port", "akka.remote-port", true},
+        {"max___size", "max_size", true},
+        {"a____b", "", false},
+        {"_a", "", false},
+        {"a_", "", false},
+        {"", "", false},
+    }
+    for _, test := range tests {
+        segs, err := envPath(test.name)
+        if (err == nil) != test.ok {
+            t.Errorf("%q: got error %v", test.name, err)
+            continue
+        }
+        if err == nil && strings.Join(segs, ".") != test.path {
+            t.Errorf("%q: got %q, expected %q", test.name, strings.Join(segs, "."), test.path)
+        }
+    }
+}
+
+func TestEnvOverrides(t *testing.T) {
+    conf := testConfig(t, `akka { loglevel = INFO, remote { port = 2552, max_size = 1k } }`)
+    env := map[string]string{
+        "HOME":                                "/root",
+        "CONFIG_FORCE_akka_loglevel":          "DEBUG",
+        "CONFIG_FORCE_akka_remote_port":       "2553",
+        "CONFIG_FORCE_akka_remote_max___size": "2k",
+        "CONFIG_FORCE_new_value":              "x y",
+        "CONFIG_FORCE_new":                    "dropped for the object",
+    }
+    overrides, err := EnvOverrides(env, "")
+    if err != nil {
+        t.Fatal(err)
+    }
+    merged := overrides.WithFallback(conf)
+    expect := `akka = (loglevel = ("DEBUG")remote = (max_size = ("2k")port = ("2553")))new = (value = ("x y"))`
+    if s := merged.String(); s != expect {
+        t.Errorf("got\n\t%s\nexpected\n\t%s", s, expect)
+    }
+    if port, err := merged.GetInt("akka.remote.port"); err != nil || port != 2553 {
+        t.Errorf("got port %d, %v", port, err)
+    }
+    v, _ := merged.GetValue("akka.remote.port")
+    if o := v.Origin().String(); o != "env:3:1" {
+        t.Errorf("got origin %s", o)
+    }
+
+    overrides, err = EnvOverrides(map[string]string{"APP_a": "1", "CONFIG_FORCE_b": "2"}, "APP_")
+    if err != nil || overrides.String() != `a = ("1")` {
+        t.Errorf("custom prefix: got %s, %v", overrides, err)
+    }
+    if _, err := EnvOverrides(map[string]string{"CONFIG_FORCE_a____b": "1"}, ""); err == nil {
+        t.Errorf("expected an error for a bad name")
+    }
+}
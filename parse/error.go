@@ -0,0 +1,47 @@
+package parse
+
+import (
+    "errors"
+    "fmt"
+)
+
+// ParseError is a single problem found while parsing, with its position
+// in the input.
+type ParseError struct {
+    Name string // name of the input, as given to Parse
+    Pos  Pos    // byte offset of the offending token
+    Line int    // 1-based line number
+    Col  int    // 1-based column, in bytes
+    Msg  string // description of the problem
+}
+
+func (e *ParseError) Error() string {
+    return fmt.Sprintf("%s:%d:%d: %s", e.Name, e.Line, e.Col, e.Msg)
+}
+
+// ErrorList is the list of errors collected by a parse in RecoverErrors
+// mode, in the order they were found.
+type ErrorList []*ParseError
+
+func (l ErrorList) Error() string {
+    switch len(l) {
+        case 0:
+            return "no errors"
+        case 1:
+            return l[0].Error()
+    }
+    return fmt.Sprintf("%s (and %d more errors)", l[0], len(l)-1)
+}
+
+// Err returns an error equivalent to this error list.
+// If the list is empty, Err returns nil.
+func (l ErrorList) Err() error {
+    if len(l) == 0 {
+        return nil
+    }
+    return l
+}
+
+// errResync is raised by errorf in RecoverErrors mode. It unwinds the
+// parser to the enclosing object field, which skips the broken input.
+var errResync = errors.New("resync")
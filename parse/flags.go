@@ -0,0 +1,142 @@
+package parse
+
+import (
+    "encoding/json"
+    "flag"
+    "fmt"
+    "strings"
+)
+
+// ParseOverrides returns a config with a value for every key=value
+// argument in args, later arguments taking precedence over earlier ones.
+// The key is a path, as for GetValue. The value is HOCON, so that a=[1,2]
+// sets a list and a={b=1} an object, which is merged with other objects
+// set for the same path; a value that is not a single HOCON value, such
+// as http://example.com, is taken as a string.
+//
+// To apply the overrides, use them as the config with everything else as
+// their fallback: overrides.WithFallback(conf).
+func ParseOverrides(args []string) (*Config, error) {
+    t := New("overrides")
+    var root Node = t.newMap(0)
+    for _, arg := range args {
+        i := strings.Index(arg, "=")
+        if i <= 0 {
+            return nil, fmt.Errorf("override %q: not key=value", arg)
+        }
+        segs, err := parsePath(arg[:i])
+        if err != nil {
+            return nil, fmt.Errorf("override %q: %v", arg, err)
+        }
+        if len(segs) == 0 {
+            return nil, fmt.Errorf("override %q: empty key", arg)
+        }
+        value, err := parseOverrideValue(arg, arg[i+1:])
+        if err != nil {
+            return nil, err
+        }
+        for j := len(segs) - 1; j >= 0; j-- {
+            m := t.newMap(0)
+            m.Nodes[segs[j]] = value
+            value = m
+        }
+        root = value.withFallback(root)
+    }
+    return &Config{root: root}, nil
+}
+
+// parseOverrideValue parses the value of the override arg.
+func parseOverrideValue(arg, value string) (Node, error) {
+    if v := singleValue(arg, "v="+value); v != nil {
+        return v, nil
+    }
+    quoted, err := json.Marshal(value)
+    if err != nil {
+        return nil, fmt.Errorf("override %q: %v", arg, err)
+    }
+    if v := singleValue(arg, "v="+string(quoted)); v != nil {
+        return v, nil
+    }
+    return nil, fmt.Errorf("override %q: bad value", arg)
+}
+
+// singleValue parses text, a single field v, and returns the value of v,
+// or nil if text is anything else.
+func singleValue(name, text string) Node {
+    tree, err := Parse(name, text)
+    if err != nil {
+        return nil
+    }
+    m, ok := tree.Root.(*MapNode)
+    if !ok || len(m.Nodes) != 1 {
+        return nil
+    }
+    return m.Nodes["v"]
+}
+
+// SplitOverrides separates the -Dkey=value arguments of args from the
+// others, so that the rest can be handed to a flag.FlagSet, which would
+// read -Dkey=value as a flag named Dkey. Arguments after "--" are left
+// alone.
+func SplitOverrides(args []string) (overrides, rest []string) {
+    for i, arg := range args {
+        if arg == "--" {
+            rest = append(rest, args[i:]...)
+            break
+        }
+        if strings.HasPrefix(arg, "-D") && len(arg) > 2 {
+            overrides = append(overrides, arg[2:])
+        } else {
+            rest = append(rest, arg)
+        }
+    }
+    return
+}
+
+// Overrides is a flag.Value that collects key=value overrides, one per
+// use of the flag, for ParseOverrides. Its Type method makes it a
+// pflag.Value as well; registered with the shorthand D, pflag accepts
+// -Dkey=value.
+type Overrides struct {
+    args []string
+}
+
+// OverridesVar defines a flag with the given name and usage on fs that
+// collects overrides into the returned Overrides.
+func OverridesVar(fs *flag.FlagSet, name, usage string) *Overrides {
+    o := new(Overrides)
+    fs.Var(o, name, usage)
+    return o
+}
+
+func (o *Overrides) String() string {
+    if o == nil {
+        return ""
+    }
+    return strings.Join(o.args, " ")
+}
+
+// Set adds an override. It returns an error if arg is not a valid
+// key=value override.
+func (o *Overrides) Set(arg string) error {
+    if _, err := ParseOverrides([]string{arg}); err != nil {
+        return err
+    }
+    o.args = append(o.args, arg)
+    return nil
+}
+
+// Type returns the name of the type of value the flag takes, for pflag.
+func (o *Overrides) Type() string {
+    return "key=value"
+}
+
+// Args returns the overrides collected so far.
+func (o *Overrides) Args() []string {
+    return o.args
+}
+
+// Config returns the overrides collected so far as a config.
+func (o *Overrides) Config() (*Config, error) {
+    return ParseOverrides(o.args)
+}
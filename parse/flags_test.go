@@ -0,0 +1,82 @@
+package parse
+
+import (
+    "flag"
+    "io/ioutil"
+    "strings"
+    "testing"
+)
+
+type overridesTest struct {
+    name   string
+    args   []string
+    ok     bool
+    result string
+}
+
+var overridesTests = []overridesTest{
+    {"empty", nil, noError, ``},
+    {"string", []string{"akka.loglevel=INFO"}, noError, `akka = (loglevel = (INFO))`},
+    {"list", []string{"a=[1, 2]"}, noError, `a = (12)`},
+    {"object", []string{"a={b=1}", "a.c=2"}, noError, `a = (b = (1)c = (2))`},
+    {"later wins", []string{"a=1", "a=2"}, noError, `a = (2)`},
+    {"quoted key", []string{`"a.b".c=1`}, noError, `a.b = (c = (1))`},
+    {"url", []string{"url=http://example.com/a#b"}, noError, `url = ("http://example.com/a#b")`},
+    {"several fields", []string{"a=1, b=2"}, noError, `a = ("1, b=2")`},
+    {"empty value", []string{"a="}, noError, `a = ("")`},
+    {"substitution", []string{"a=${b}"}, noError, `a = (${b})`},
+    {"no value", []string{"a"}, hasError, ``},
+    {"no key", []string{"=1"}, hasError, ``},
+    {"bad key", []string{"a..b=1"}, hasError, ``},
+}
+
+func TestParseOverrides(t *testing.T) {
+    for _, test := range overridesTests {
+        conf, err := ParseOverrides(test.args)
+        switch {
+            case err == nil && !test.ok:
+            t.Errorf("%s: expected error; got none", test.name)
+            continue
+            case err != nil && test.ok:
+            t.Errorf("%s: unexpected error: %v", test.name, err)
+            continue
+            case err != nil && !test.ok:
+            continue
+        }
+        if result := conf.String(); result != test.result {
+            t.Errorf("%s: got\n\t%v\nexpected\n\t%v", test.name, result, test.result)
+        }
+    }
+}
+
+func TestSplitOverrides(t *testing.T) {
+    overrides, rest := SplitOverrides([]string{"-Da=1", "-v", "-D", "-Db.c=x", "file", "--", "-Dd=2"})
+    if s := strings.Join(overrides, " "); s != "a=1 b.c=x" {
+        t.Errorf("got overrides %q", s)
+    }
+    if s := strings.Join(rest, " "); s != "-v -D file -- -Dd=2" {
+        t.Errorf("got rest %q", s)
+    }
+}
+
+func TestOverridesFlag(t *testing.T) {
+    fs := flag.NewFlagSet("test", flag.ContinueOnError)
+    fs.SetOutput(ioutil.Discard)
+    o := OverridesVar(fs, "D", "set a config value")
+    if err := fs.Parse([]string{"-D", "a=1", "-D=b.c=[x]", "arg"}); err != nil {
+        t.Fatal(err)
+    }
+    conf, err := o.Config()
+    if err != nil {
+        t.Fatal(err)
+    }
+    if s := conf.String(); s != `a = (1)b = (c = (x))` {
+        t.Errorf("got %s", s)
+    }
+    if err := fs.Parse([]string{"-D", "novalue"}); err == nil {
+        t.Errorf("expected an error for a bad override")
+    }
+    if o.Type() != "key=value" || len(o.Args()) != 2 {
+        t.Errorf("got type %q and args %q", o.Type(), o.Args())
+    }
+}
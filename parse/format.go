@@ -0,0 +1,537 @@
+package parse
+
+import (
+    "bytes"
+    "sort"
+    "strings"
+)
+
+// FormatOptions control the output of FormatSource.
+type FormatOptions struct {
+    Indent   string // indent of each level; two spaces if empty
+    Colon    bool   // separate keys and values with ":" instead of "="
+    SortKeys bool   // sort the fields of objects by key
+}
+
+// FormatSource rewrites the HOCON text src, read from the file name, in a
+// canonical style: one field per line, indented by nesting level, keys and
+// values separated by " = " (or ": "), object values opened on the line of
+// their key, at most one blank line in a row, and the comments ending
+// consecutive lines aligned. Objects and lists written on one line stay on
+// one line, with normalized spacing. Comments are kept and the values are unchanged: the result
+// parses to a config equal to that of src. Formatting the result again
+// returns it unchanged. Include statements are kept as they are and the
+// files they name are not read.
+//
+// With SortKeys the fields of each object are sorted by the first segment
+// of their key. Fields with the same first segment keep their order, since
+// later ones override or merge with earlier ones, and no field moves across
+// an include statement.
+func FormatSource(name string, src []byte, opts *FormatOptions) ([]byte, error) {
+    if opts == nil {
+        opts = new(FormatOptions)
+    }
+    text := strings.Replace(string(src), "\r\n", "\n", -1)
+    t := New(name)
+    t.noIncludes = true
+    if _, err := t.Parse(text); err != nil {
+        return nil, err
+    }
+    root := &fmtNode{open: item{typ: itemEOF}}
+    root.children = groupTokens(lexAll(name, text), new(int))
+    if opts.SortKeys {
+        sortFields(root, false)
+    }
+    p := &printer{opts: opts, indent: opts.Indent}
+    if p.indent == "" {
+        p.indent = "  "
+    }
+    p.block(root.children, false)
+    return p.bytes(), nil
+}
+
+// lexAll returns the tokens of text with its comments, ending with EOF.
+func lexAll(name, text string) []item {
+    l := lex(name, text, ParseComments)
+    var items []item
+    for {
+        token := l.nextItem()
+        items = append(items, token)
+        if token.typ == itemEOF || token.typ == itemError {
+            l.drain()
+            return items
+        }
+    }
+}
+
+// fmtNode is a token, or a bracketed group of tokens.
+type fmtNode struct {
+    open     item       // the token, or the opening bracket of a group
+    children []*fmtNode // the contents of a group
+    close    item       // the closing bracket of a group
+    group    bool
+}
+
+// groupTokens builds the nodes of the tokens from items[*i] up to the end
+// of the enclosing group.
+func groupTokens(items []item, i *int) []*fmtNode {
+    var nodes []*fmtNode
+    for *i < len(items) {
+        token := items[*i]
+        *i++
+        switch token.typ {
+            case itemEOF:
+                return nodes
+            case itemCloseCurly, itemCloseSquare:
+                *i--
+                return nodes
+            case itemOpenCurly, itemOpenSquare:
+                n := &fmtNode{open: token, group: true}
+                n.children = groupTokens(items, i)
+                if *i < len(items) {
+                    n.close = items[*i]
+                    *i++
+                }
+                nodes = append(nodes, n)
+            default:
+                nodes = append(nodes, &fmtNode{open: token})
+        }
+    }
+    return nodes
+}
+
+func (n *fmtNode) is(typ itemType) bool {
+    return !n.group && n.open.typ == typ
+}
+
+func (n *fmtNode) isList() bool {
+    return n.group && n.open.typ == itemOpenSquare
+}
+
+// multiline reports whether a group that is not empty spans lines.
+func (n *fmtNode) multiline() bool {
+    if nextCode(n.children, 0) == len(n.children) {
+        return false
+    }
+    for _, c := range n.children {
+        if c.is(itemNewLine) || c.group && c.multiline() {
+            return true
+        }
+        if c.is(itemComment) && strings.Contains(c.open.val, "\n") {
+            return true
+        }
+    }
+    return false
+}
+
+func isSeparator(n *fmtNode) bool {
+    return n.is(itemEquals) || n.is(itemColon) || n.is(itemPlusEquals)
+}
+
+func isBlank(n *fmtNode) bool {
+    return n.is(itemSpace) || n.is(itemNewLine)
+}
+
+// nextCode returns the index of the first node from i on that is not
+// whitespace, or len(nodes).
+func nextCode(nodes []*fmtNode, i int) int {
+    for i < len(nodes) && isBlank(nodes[i]) {
+        i++
+    }
+    return i
+}
+
+// sortFields sorts the fields of the objects in n and below.
+func sortFields(n *fmtNode, list bool) {
+    for _, c := range n.children {
+        if c.group {
+            sortFields(c, c.isList())
+        }
+    }
+    if list {
+        return
+    }
+    head, fields, tail := splitFields(n.children)
+    if len(fields) < 2 {
+        return
+    }
+    sep := &fmtNode{open: item{typ: itemNewLine, val: "\n"}}
+    if n.group && !n.multiline() {
+        sep = &fmtNode{open: item{typ: itemComma, val: ","}}
+    }
+    // Sort the runs of fields between include statements.
+    start := 0
+    for i := 0; i <= len(fields); i++ {
+        if i == len(fields) || fields[i].include {
+            run := fields[start:i]
+            sort.SliceStable(run, func(a, b int) bool {
+                return run[a].key < run[b].key
+            })
+            start = i + 1
+        }
+    }
+    children := head
+    for i, f := range fields {
+        if i > 0 || sep.is(itemNewLine) {
+            children = append(children, sep)
+        }
+        children = append(children, f.nodes...)
+    }
+    if len(tail) > 0 {
+        children = append(append(children, sep), tail...)
+    }
+    n.children = children
+}
+
+// field is a field of an object for sorting, with the comments above it.
+type field struct {
+    nodes   []*fmtNode
+    key     string // the first segment of the key
+    include bool
+}
+
+// splitFields splits the contents of an object into its fields. The
+// comments on the line of the opening bracket make up head, and those
+// after the last field tail.
+func splitFields(nodes []*fmtNode) (head []*fmtNode, fields []*field, tail []*fmtNode) {
+    cur := new(field)
+    hasCode := false
+    newline := false // whether a line break came after the last field
+    end := func() {
+        if hasCode {
+            fields = append(fields, cur)
+        } else {
+            tail = cur.nodes
+        }
+        cur, hasCode = new(field), false
+    }
+    for i, n := range nodes {
+        boundary := (n.is(itemNewLine) || n.is(itemComma)) && hasCode
+        if boundary {
+            // A line break after a separator, even with a comment
+            // between them, or before a value, does not end the field.
+            prev := i - 1
+            for prev >= 0 && (isBlank(nodes[prev]) || nodes[prev].is(itemComment)) {
+                prev--
+            }
+            next := nextCode(nodes, i+1)
+            if prev >= 0 && isSeparator(nodes[prev]) || next < len(nodes) && (isSeparator(nodes[next]) || nodes[next].group && !nodes[next].isList()) {
+                boundary = false
+            }
+        }
+        if boundary {
+            end()
+            newline = n.is(itemNewLine)
+            continue
+        }
+        if n.is(itemNewLine) {
+            newline = true
+        }
+        if n.is(itemComment) && !hasCode && !newline {
+            // The comment ends the line of the previous field.
+            if len(fields) > 0 {
+                last := fields[len(fields)-1]
+                last.nodes = append(last.nodes, n)
+            } else {
+                head = append(head, n)
+            }
+            continue
+        }
+        if len(cur.nodes) == 0 && isBlank(n) || n.is(itemComma) {
+            continue
+        }
+        if !hasCode && !n.is(itemComment) && !isBlank(n) {
+            hasCode = true
+            cur.key = fieldKey(n)
+            cur.include = n.open.typ == itemUnquotedText && n.open.val == "include" && i+1 < len(nodes) && nodes[i+1].is(itemSpace)
+        }
+        cur.nodes = append(cur.nodes, n)
+    }
+    end()
+    return head, fields, tail
+}
+
+// fieldKey returns the first segment of the key starting with n.
+func fieldKey(n *fmtNode) string {
+    if n.is(itemString) {
+        return unquoteToken(n.open)
+    }
+    if i := strings.Index(n.open.val, "."); i >= 0 {
+        return n.open.val[:i]
+    }
+    return n.open.val
+}
+
+// line is a line of output.
+type line struct {
+    depth   int
+    code    string
+    comment string // a comment following the code
+}
+
+// Stages of a field on the current line.
+const (
+    stageStart = iota // nothing written yet
+    stageKey          // a key written
+    stageSep          // a separator written, the value is due
+    stageValue        // a value written
+)
+
+// printer lays out the token tree.
+type printer struct {
+    opts   *FormatOptions
+    indent string
+    lines  []line
+    cur    line
+    depth  int
+    list   bool // printing the elements of a list
+    // State of the current line.
+    started bool   // cur has been started
+    stage   int    // how much of a field has been written
+    newline bool   // a line break came after the last code
+    blank   bool   // a blank line is due before the next line
+    brk     bool   // a line break is due before the next code
+    force   bool   // the break is due even between key and value
+    space   string // the space due before the next token
+}
+
+// newLine ends the current line, if any, and starts a new one.
+func (p *printer) newLine() {
+    if p.started {
+        p.lines = append(p.lines, p.cur)
+        if p.blank {
+            p.lines = append(p.lines, line{})
+        }
+    }
+    p.cur = line{depth: p.depth}
+    p.started, p.stage = true, stageStart
+    p.blank, p.brk, p.force, p.space = false, false, false, ""
+}
+
+// write adds code to the current line, breaking it first if due.
+func (p *printer) write(s string) {
+    if !p.started || p.brk || p.cur.comment != "" {
+        p.newLine()
+    }
+    p.cur.code += p.space + s
+    p.space, p.newline = "", false
+}
+
+// block prints the contents of a multiline group, or of the whole input.
+func (p *printer) block(nodes []*fmtNode, list bool) {
+    p.list = list
+    newlines := 0
+    seen := false // whether code or a comment came before, to allow a blank line
+    for i, n := range nodes {
+        switch {
+            case n.is(itemSpace):
+                if p.stage == stageKey || p.stage == stageValue {
+                    p.space = n.open.val
+                }
+            case n.is(itemNewLine):
+                newlines++
+                p.brk, p.newline, p.space = true, true, ""
+            case n.is(itemComma):
+                p.brk, p.space = true, ""
+            case n.is(itemComment) && !inlineComment(nodes, i):
+                p.breakLine(&newlines, seen)
+                seen = true
+                if p.cur.code != "" && p.cur.comment == "" && !p.newline {
+                    p.cur.comment = n.open.val
+                } else {
+                    p.brk, p.space = true, ""
+                    p.write("")
+                    p.cur.comment = n.open.val
+                }
+                p.brk, p.force = true, true
+            default:
+                // A break between a key and its separator or value is
+                // dropped, unless a comment forces it.
+                if p.brk && !p.force && !list {
+                    if p.stage == stageSep || p.stage == stageKey && (isSeparator(n) || isObject(n)) {
+                        p.brk = false
+                    }
+                }
+                p.breakLine(&newlines, seen)
+                seen = true
+                p.code(nodes, i)
+        }
+    }
+}
+
+// inlineComment reports whether the comment nodes[i] is followed by code
+// on its line, so that it is printed in place.
+func inlineComment(nodes []*fmtNode, i int) bool {
+    c := nodes[i].open.val
+    if !strings.HasPrefix(c, leftComment) || strings.Contains(c, "\n") {
+        return false
+    }
+    i++
+    for i < len(nodes) && nodes[i].is(itemSpace) {
+        i++
+    }
+    return i < len(nodes) && !nodes[i].is(itemNewLine) && !nodes[i].is(itemComment)
+}
+
+func isObject(n *fmtNode) bool {
+    return n.group && !n.isList()
+}
+
+// breakLine notes a blank line before the next one if the input had one
+// since the last code of the block.
+func (p *printer) breakLine(newlines *int, seen bool) {
+    if p.brk && *newlines > 1 && seen {
+        p.blank = true
+    }
+    *newlines = 0
+}
+
+// code prints the token or group nodes[i].
+func (p *printer) code(nodes []*fmtNode, i int) {
+    n := nodes[i]
+    switch {
+        case isSeparator(n):
+            next := nextCode(nodes, i+1)
+            if n.is(itemPlusEquals) {
+                p.space = " "
+                p.write("+=")
+            } else if next < len(nodes) && isObject(nodes[next]) && !p.list {
+                // An object value follows the key directly.
+                p.space = ""
+                return
+            } else if p.opts.Colon {
+                p.space = ""
+                p.write(":")
+            } else {
+                p.space = " "
+                p.write("=")
+            }
+            p.space, p.stage = " ", stageSep
+        case n.group:
+            if isObject(n) && p.stage == stageKey {
+                p.space = " "
+            }
+            p.group(n)
+            p.stage = stageValue
+        case n.is(itemComment):
+            p.write(n.open.val)
+            if p.stage == stageStart || p.stage == stageSep {
+                p.space = " "
+            }
+        default:
+            p.write(n.open.val)
+            switch {
+                case p.stage == stageStart && p.list, p.stage == stageSep:
+                    p.stage = stageValue
+                case p.stage == stageStart:
+                    p.stage = stageKey
+            }
+    }
+}
+
+// group prints a bracketed group.
+func (p *printer) group(n *fmtNode) {
+    open, close := n.open.val, n.close.val
+    if close == "" {
+        close = map[string]string{"{": "}", "[": "]"}[open]
+    }
+    list, stage := p.list, p.stage
+    p.write(open)
+    if n.multiline() {
+        p.depth++
+        p.brk, p.force = true, false
+        p.block(n.children, n.isList())
+        p.depth--
+        p.brk, p.force, p.space = true, false, ""
+    } else {
+        p.inline(n)
+    }
+    p.write(close)
+    p.list, p.stage = list, stage
+}
+
+// inline prints the contents of a group on one line.
+func (p *printer) inline(n *fmtNode) {
+    p.list, p.stage = n.isList(), stageStart
+    first := true
+    for i, c := range n.children {
+        switch {
+            case isBlank(c):
+                if p.stage == stageKey || p.stage == stageValue {
+                    p.space = c.open.val
+                }
+            case c.is(itemComma):
+                p.space = ""
+                p.write(",")
+                p.space, p.stage = " ", stageStart
+            default:
+                if first && !p.list {
+                    p.space = " "
+                } else if first {
+                    p.space = ""
+                }
+                first = false
+                p.code(n.children, i)
+        }
+    }
+    if !first && !p.list {
+        p.space = " "
+    }
+}
+
+// bytes returns the output, with trailing comments aligned.
+func (p *printer) bytes() []byte {
+    if p.started {
+        p.lines = append(p.lines, p.cur)
+    }
+    var b bytes.Buffer
+    for i := 0; i < len(p.lines); {
+        // Align the comments of a run of lines with code and comments at
+        // the same depth.
+        j, width := i, 0
+        for j < len(p.lines) && p.lines[j].code != "" && p.lines[j].comment != "" && p.lines[j].depth == p.lines[i].depth {
+            if w := p.width(p.lines[j]); w > width {
+                width = w
+            }
+            j++
+        }
+        if j == i {
+            p.writeLine(&b, p.lines[i], 0)
+            i++
+            continue
+        }
+        for ; i < j; i++ {
+            p.writeLine(&b, p.lines[i], width)
+        }
+    }
+    out := bytes.TrimLeft(b.Bytes(), "\n")
+    out = bytes.TrimRight(out, "\n")
+    if len(out) > 0 {
+        out = append(out, '\n')
+    }
+    return out
+}
+
+func (p *printer) width(l line) int {
+    return len(strings.Repeat(p.indent, l.depth)) + len([]rune(l.code))
+}
+
+func (p *printer) writeLine(b *bytes.Buffer, l line, width int) {
+    if l.code == "" && l.comment == "" {
+        b.WriteByte('\n')
+        return
+    }
+    s := strings.Repeat(p.indent, l.depth) + l.code
+    if l.comment != "" {
+        if l.code != "" {
+            pad := 1
+            if width > 0 {
+                pad = width - p.width(l) + 1
+            }
+            s += strings.Repeat(" ", pad)
+        }
+        s += strings.TrimRight(l.comment, " \t")
+    }
+    b.WriteString(s)
+    b.WriteByte('\n')
+}
@@ -0,0 +1,95 @@
+package parse
+
+import (
+    "testing"
+)
+
+var formatTests = []struct {
+    name   string
+    opts   FormatOptions
+    input  string
+    result string
+}{
+    {"empty", FormatOptions{}, "\n\n", ""},
+    {"fields", FormatOptions{}, "a:1, b =2\n  c   =  x  y\n\n\n\nd += 3", "a = 1\nb = 2\nc = x  y\n\nd += 3\n"},
+    {"objects", FormatOptions{}, "a = {\n  b.c = 1\n        d { e = [1 ,2] }\n}\nf\n{\n}\n", "a {\n  b.c = 1\n  d { e = [1, 2] }\n}\nf {}\n"},
+    {"lists", FormatOptions{}, "a = [\n1, 2,\n  [3, 4], {b = 5}\n]", "a = [\n  1\n  2\n  [3, 4]\n  { b = 5 }\n]\n"},
+    {"comments", FormatOptions{}, `# header
+
+a { // opening
+  b = 1 # one
+  ccc = 2 // two
+
+  /* three
+     lines */
+  d = /* inline */ 3
+  // last
+}
+`, `# header
+
+a { // opening
+  b = 1   # one
+  ccc = 2 // two
+
+  /* three
+     lines */
+  d = /* inline */ 3
+  // last
+}
+`},
+    {"json", FormatOptions{}, `{"a": {"b": [true, null]}, "c": "d"}`, `{ "a" { "b" = [true, null] }, "c" = "d" }` + "\n"},
+    {"colon", FormatOptions{Colon: true, Indent: "    "}, "a = 1\nb {\nc = ${a}\n}", "a: 1\nb {\n    c: ${a}\n}\n"},
+    {"sort", FormatOptions{SortKeys: true}, `c = 3 # three
+b { z = 1, y = 2 }
+// about a
+a.x = 1
+include "other.conf"
+b.w = 0
+a = 2
+`, `// about a
+a.x = 1
+b { y = 2, z = 1 }
+c = 3 # three
+include "other.conf"
+a = 2
+b.w = 0
+`},
+    {"comment after separator", FormatOptions{}, "b = 2\na = # c\n 1\n", "b = 2\na = # c\n1\n"},
+    {"sort comment after separator", FormatOptions{SortKeys: true}, "b = 2\na = # c\n 1\n", "a = # c\n1\nb = 2\n"},
+}
+
+func TestFormatSource(t *testing.T) {
+    for _, test := range formatTests {
+        out, err := FormatSource(test.name, []byte(test.input), &test.opts)
+        if err != nil {
+            t.Errorf("%s: %v", test.name, err)
+            continue
+        }
+        if string(out) != test.result {
+            t.Errorf("%s: got\n%s\nexpected\n%s", test.name, out, test.result)
+            continue
+        }
+        again, err := FormatSource(test.name, out, &test.opts)
+        if err != nil || string(again) != string(out) {
+            t.Errorf("%s: formatting again gave\n%s\n%v", test.name, again, err)
+        }
+        before, err := Parse(test.name, test.input)
+        if err != nil {
+            t.Fatalf("%s: %v", test.name, err)
+        }
+        after, err := Parse(test.name, string(out))
+        if err != nil {
+            t.Errorf("%s: %v", test.name, err)
+            continue
+        }
+        if !after.GetConfig().Equal(before.GetConfig()) {
+            t.Errorf("%s: the config changed to %s", test.name, after.Root)
+        }
+    }
+}
+
+func TestFormatSourceError(t *testing.T) {
+    if _, err := FormatSource("test", []byte("a = [1"), nil); err == nil {
+        t.Error("expected an error")
+    }
+}
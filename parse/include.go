@@ -0,0 +1,147 @@
+package parse
+
+import (
+    "io/ioutil"
+    "os"
+    "path/filepath"
+    "strings"
+)
+
+// ParseFile parses the file at path. Relative paths in its include
+// statements, and in those of the files it includes, start from the
+// directory of the including file.
+func ParseFile(path string) (*Tree, error) {
+    text, err := ioutil.ReadFile(path)
+    if err != nil {
+        return nil, err
+    }
+    return NewFile(path).Parse(string(text))
+}
+
+// NewFile allocates a parse tree for the text of the file at path, whose
+// include statements are relative to the directory of path, as with
+// ParseFile. It is for text that is not read from the file, such as the
+// contents of an editor buffer.
+func NewFile(path string) *Tree {
+    t := New(path)
+    t.dir = filepath.Dir(path)
+    t.including = []string{filepath.Clean(path)}
+    return t
+}
+
+// isInclude reports whether token, the first of a field, starts an include
+// statement rather than a key: the word include followed by a quoted file
+// name or one of file(, required( and friends.
+func (t *Tree) isInclude(token item) bool {
+    if token.typ != itemUnquotedText || token.val != "include" {
+        return false
+    }
+    space := t.next()
+    if space.typ != itemSpace {
+        t.backup()
+        return false
+    }
+    target := t.peek()
+    t.backup2(space)
+    switch target.typ {
+        case itemString:
+            return true
+        case itemUnquotedText:
+            for _, f := range []string{"file(", "required(", "url(", "classpath("} {
+                if strings.HasPrefix(target.val, f) && strings.HasSuffix(target.val, "(") {
+                    return true
+                }
+            }
+    }
+    return false
+}
+
+// parseInclude parses the target of an include statement and merges the
+// fields of the included file into result, as if they were written in its
+// place. A missing file is ignored unless it is marked required(...).
+func (t *Tree) parseInclude(result *MapNode) {
+    token := t.nextNonSpace()
+    pos := token.pos
+    required := false
+    parens := 0
+    if token.typ == itemUnquotedText {
+        switch token.val {
+            case "file(":
+                parens = 1
+            case "required(":
+                required, parens = true, 1
+            case "required(file(":
+                required, parens = true, 2
+            default:
+                t.errorAt(pos, "include %s...) is not supported", token.val)
+        }
+        token = t.nextNonSpace()
+        if token.typ != itemString {
+            t.unexpected(token, "include")
+        }
+    }
+    name := t.unquote(token)
+    for parens > 0 {
+        end := t.nextNonSpace()
+        if end.typ != itemUnquotedText || strings.Trim(end.val, ")") != "" || len(end.val) > parens {
+            t.expected(end, ")")
+        }
+        parens -= len(end.val)
+    }
+
+    if t.noIncludes {
+        return
+    }
+    included := t.include(pos, name, required)
+    if included == nil {
+        return
+    }
+    for _, k := range included.sortedKeys() {
+        newValue := included.Nodes[k]
+        if existing, ok := result.Nodes[k]; ok {
+            newValue = newValue.withFallback(existing)
+        }
+        result.Nodes[k] = newValue
+    }
+}
+
+// include parses the file name, relative to the directory of t, and
+// returns its root object. It returns nil if the file does not exist and
+// is not required.
+func (t *Tree) include(pos Pos, name string, required bool) *MapNode {
+    path := name
+    if !filepath.IsAbs(path) {
+        path = filepath.Join(t.dir, path)
+    }
+    t.Includes = append(t.Includes, path)
+    for _, p := range t.including {
+        if p == path {
+            t.errorAt(pos, "include cycle: %s", strings.Join(append(t.including, path), " -> "))
+        }
+    }
+    text, err := ioutil.ReadFile(path)
+    if err != nil {
+        if os.IsNotExist(err) && !required {
+            return nil
+        }
+        t.errorAt(pos, "include: %v", err)
+    }
+    sub := New(path)
+    sub.Mode = t.Mode
+    sub.dir = filepath.Dir(path)
+    sub.including = append(append([]string(nil), t.including...), path)
+    _, err = sub.Parse(string(text))
+    t.Includes = append(t.Includes, sub.Includes...)
+    if t.Mode&RecoverErrors != 0 {
+        t.Errors = append(t.Errors, sub.Errors...)
+    } else if err != nil {
+        // The error already names the included file.
+        t.Root = nil
+        panic(err)
+    }
+    m, ok := sub.Root.(*MapNode)
+    if !ok {
+        t.errorAt(pos, "included file %s is not an object", path)
+    }
+    return m
+}
@@ -0,0 +1,107 @@
+package parse
+
+import (
+    "io/ioutil"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+)
+
+// writeFiles writes the named files into a new temporary directory.
+func writeFiles(t *testing.T, files map[string]string) string {
+    dir, err := ioutil.TempDir("", "config")
+    if err != nil {
+        t.Fatal(err)
+    }
+    for name, text := range files {
+        path := filepath.Join(dir, name)
+        if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+            t.Fatal(err)
+        }
+        if err := ioutil.WriteFile(path, []byte(text), 0644); err != nil {
+            t.Fatal(err)
+        }
+    }
+    return dir
+}
+
+func TestInclude(t *testing.T) {
+    dir := writeFiles(t, map[string]string{
+        "main.conf": `a = 1
+include "sub/defaults.conf"
+b = 2
+c { include file("c.conf") }
+include required(file("sub/defaults.conf"))
+include "missing.conf"`,
+        "sub/defaults.conf": `a = 10, b = 20, d { e = 1 }
+include "more.conf"`,
+        "sub/more.conf": `d { f = 2 }`,
+        "c.conf":        `x = y`,
+    })
+    defer os.RemoveAll(dir)
+    tree, err := ParseFile(filepath.Join(dir, "main.conf"))
+    if err != nil {
+        t.Fatal(err)
+    }
+    expect := `a = (10)b = (20)c = (x = (y))d = (e = (1)f = (2))`
+    if s := tree.Root.String(); s != expect {
+        t.Errorf("got\n\t%s\nexpected\n\t%s", s, expect)
+    }
+    var includes []string
+    for _, inc := range tree.Includes {
+        rel, _ := filepath.Rel(dir, inc)
+        includes = append(includes, filepath.ToSlash(rel))
+    }
+    expectIncludes := "sub/defaults.conf sub/more.conf c.conf sub/defaults.conf sub/more.conf missing.conf"
+    if s := strings.Join(includes, " "); s != expectIncludes {
+        t.Errorf("got includes %s, expected %s", s, expectIncludes)
+    }
+    // Values keep the origin of the file that defined them.
+    if o := tree.GetConfig().Origin(); o.Name != filepath.Join(dir, "main.conf") {
+        t.Errorf("root origin %s", o)
+    }
+    x, _ := tree.GetConfig().GetValue("c.x")
+    if o := x.Origin(); o.Name != filepath.Join(dir, "c.conf") || o.Line != 1 || o.Col != 5 {
+        t.Errorf("c.x origin %s", o)
+    }
+}
+
+func TestIncludeKey(t *testing.T) {
+    tree, err := Parse("test", `include = 1, include.a = 2`)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if s := tree.Root.String(); s != `include = (a = (2))` {
+        t.Errorf("got %s", s)
+    }
+}
+
+func TestIncludeErrors(t *testing.T) {
+    dir := writeFiles(t, map[string]string{
+        "required.conf": `include required("missing.conf")`,
+        "cycle.conf":    `include "cycle2.conf"`,
+        "cycle2.conf":   `include "cycle.conf"`,
+        "broken.conf":   `include "bad.conf"`,
+        "bad.conf":      "a = 1\nb = }",
+        "list.conf":     `include "array.conf"`,
+        "array.conf":    `[1, 2]`,
+        "url.conf":      `include url("http://example.com/a.conf")`,
+    })
+    defer os.RemoveAll(dir)
+    tests := []struct {
+        file, err string
+    }{
+        {"required.conf", "missing.conf"},
+        {"cycle.conf", "include cycle"},
+        {"broken.conf", "bad.conf:2"},
+        {"list.conf", "is not an object"},
+        {"url.conf", "not supported"},
+    }
+    for _, test := range tests {
+        _, err := ParseFile(filepath.Join(dir, test.file))
+        if err == nil || !strings.Contains(err.Error(), test.err) {
+            t.Errorf("%s: got error %v, expected %q", test.file, err, test.err)
+        }
+    }
+}
@@ -0,0 +1,547 @@
+package parse
+
+import (
+    "bytes"
+    "encoding/json"
+    "fmt"
+    "math"
+    "regexp"
+    "sort"
+    "strconv"
+    "strings"
+    "unicode/utf8"
+)
+
+// jsonSchemaDraft identifies the JSON Schema dialect read and written.
+const jsonSchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// ToJSONSchema returns a JSON Schema (draft 2020-12) document describing
+// c as a reference config: every setting of c is a property, whose type is
+// that of its value and whose default is the value itself. Lists are
+// arrays of the type of their elements if they all have the same one;
+// null values accept anything. The descriptions, by the path of the
+// setting as given by Path.String, may come from Descriptions. c must be
+// resolved.
+func (c *Config) ToJSONSchema(descriptions map[string]string) ([]byte, error) {
+    if !c.IsResolved() {
+        return nil, fmt.Errorf("ToJSONSchema needs a resolved config")
+    }
+    var root Node = &MapNode{NodeType: NodeMap, Nodes: map[string]Node{}}
+    if c.root != nil {
+        root = c.root
+    }
+    if err := checkPlain(root, FormatJSON); err != nil {
+        return nil, err
+    }
+    s := jsonSchemaOf(Path{}, root, descriptions)
+    s["$schema"] = jsonSchemaDraft
+    var b bytes.Buffer
+    enc := json.NewEncoder(&b)
+    enc.SetEscapeHTML(false)
+    enc.SetIndent("", "  ")
+    if err := enc.Encode(s); err != nil {
+        return nil, err
+    }
+    return b.Bytes(), nil
+}
+
+// jsonSchemaOf returns the schema of the value n at path.
+func jsonSchemaOf(path Path, n Node, descriptions map[string]string) map[string]interface{} {
+    s := make(map[string]interface{})
+    if d := descriptions[path.String()]; d != "" {
+        s["description"] = d
+    }
+    switch v := n.(type) {
+        case *MapNode:
+            s["type"] = "object"
+            if len(v.Nodes) > 0 {
+                props := make(map[string]interface{})
+                for k, child := range v.Nodes {
+                    props[k] = jsonSchemaOf(path.child(k), child, descriptions)
+                }
+                s["properties"] = props
+            }
+            return s
+        case *ListNode:
+            s["type"] = "array"
+            elem := ""
+            for i, e := range v.Nodes {
+                if t := jsonType(e); i == 0 || t == elem {
+                    elem = t
+                } else {
+                    elem = ""
+                    break
+                }
+            }
+            if elem != "" && elem != "null" {
+                s["items"] = map[string]interface{}{"type": elem}
+            }
+        case *NilNode:
+        default:
+            s["type"] = jsonType(n)
+    }
+    var b bytes.Buffer
+    writeJSON(&b, n, "")
+    s["default"] = json.RawMessage(b.Bytes())
+    return s
+}
+
+// jsonType returns the JSON Schema type of n.
+func jsonType(n Node) string {
+    switch v := n.(type) {
+        case *MapNode:
+            return "object"
+        case *ListNode:
+            return "array"
+        case *NilNode:
+            return "null"
+        case *BoolNode:
+            return "boolean"
+        case *NumberNode:
+            if v.IsInt || v.BigInt != nil {
+                return "integer"
+            }
+            return "number"
+    }
+    return "string"
+}
+
+// JSONSchema is a JSON Schema document that configs can be validated
+// against.
+//
+// Validate supports the validation keywords of draft 2020-12 that apply
+// to config values: type, enum, const, minimum, maximum, exclusiveMinimum,
+// exclusiveMaximum, multipleOf, minLength, maxLength, pattern, items,
+// prefixItems, minItems, maxItems, uniqueItems, properties,
+// patternProperties, additionalProperties, required, minProperties,
+// maxProperties, allOf, anyOf, oneOf, not, and $ref to the same document.
+// Other keywords, such as format, are ignored.
+type JSONSchema struct {
+    root    interface{}
+    regexps map[string]*regexp.Regexp
+}
+
+// ParseJSONSchema parses a JSON Schema document.
+func ParseJSONSchema(data []byte) (*JSONSchema, error) {
+    dec := json.NewDecoder(bytes.NewReader(data))
+    dec.UseNumber()
+    var root interface{}
+    if err := dec.Decode(&root); err != nil {
+        return nil, fmt.Errorf("JSON Schema: %v", err)
+    }
+    switch root.(type) {
+        case bool, map[string]interface{}:
+        default:
+            return nil, fmt.Errorf("JSON Schema: not an object or boolean")
+    }
+    s := &JSONSchema{root: root, regexps: make(map[string]*regexp.Regexp)}
+    if err := s.compile(root); err != nil {
+        return nil, err
+    }
+    return s, nil
+}
+
+// compile compiles the patterns of the schema v and of its subschemas.
+// The values of keywords such as default, const, enum and examples are
+// data, not schemas, and are left alone.
+func (s *JSONSchema) compile(v interface{}) error {
+    schema, ok := v.(map[string]interface{})
+    if !ok {
+        return nil
+    }
+    if p, ok := schema["pattern"].(string); ok {
+        if err := s.regexp(p); err != nil {
+            return err
+        }
+    }
+    for k, sub := range schema {
+        var subs []interface{}
+        switch k {
+            case "properties", "patternProperties", "$defs", "definitions":
+                m, _ := sub.(map[string]interface{})
+                for name, sub := range m {
+                    if k == "patternProperties" {
+                        if err := s.regexp(name); err != nil {
+                            return err
+                        }
+                    }
+                    subs = append(subs, sub)
+                }
+            case "items", "prefixItems", "allOf", "anyOf", "oneOf":
+                if l, ok := sub.([]interface{}); ok {
+                    subs = l
+                } else {
+                    subs = []interface{}{sub}
+                }
+            case "additionalProperties", "not":
+                subs = []interface{}{sub}
+        }
+        for _, sub := range subs {
+            if err := s.compile(sub); err != nil {
+                return err
+            }
+        }
+    }
+    return nil
+}
+
+func (s *JSONSchema) regexp(p string) error {
+    re, err := regexp.Compile(p)
+    if err != nil {
+        return fmt.Errorf("JSON Schema: pattern %q: %v", p, err)
+    }
+    s.regexps[p] = re
+    return nil
+}
+
+// Validate checks conf, which must be resolved, against the schema and
+// returns all the violations found as a ValidationErrors, sorted by path,
+// or nil. Values are converted as by the getters, so that a string that
+// reads as a number is a number and any scalar is a string.
+func (s *JSONSchema) Validate(conf *Config) error {
+    if !conf.IsResolved() {
+        return fmt.Errorf("Validate needs a resolved config")
+    }
+    var root Node = &MapNode{NodeType: NodeMap, Nodes: map[string]Node{}}
+    if conf.root != nil {
+        root = conf.root
+    }
+    var errs ValidationErrors
+    s.check(&errs, Path{}, root, s.root, 0)
+    sort.SliceStable(errs, func(i, j int) bool {
+        return errs[i].Path.String() < errs[j].Path.String()
+    })
+    return errs.Err()
+}
+
+// maxRefDepth bounds the nesting of $ref, against cycles.
+const maxRefDepth = 64
+
+// check checks the value n at path against the schema v.
+func (s *JSONSchema) check(errs *ValidationErrors, path Path, n Node, v interface{}, depth int) {
+    bad := func(format string, args ...interface{}) {
+        *errs = append(*errs, &ValidationError{Path: path, Origin: originOf(n), Msg: fmt.Sprintf(format, args...)})
+    }
+    switch schema := v.(type) {
+        case bool:
+            if !schema {
+                bad("not allowed")
+            }
+            return
+        case map[string]interface{}:
+            s.checkObject(errs, path, n, schema, depth, bad)
+    }
+}
+
+func (s *JSONSchema) checkObject(errs *ValidationErrors, path Path, n Node, schema map[string]interface{}, depth int, bad func(string, ...interface{})) {
+    var c Config
+    if ref, ok := schema["$ref"].(string); ok {
+        target, err := s.resolveRef(ref)
+        switch {
+            case err != nil:
+                bad("%v", err)
+            case depth >= maxRefDepth:
+                bad("$ref %s nested too deep", ref)
+            default:
+                s.check(errs, path, n, target, depth+1)
+        }
+    }
+    if t, ok := schema["type"]; ok {
+        var types []string
+        switch t := t.(type) {
+            case string:
+                types = []string{t}
+            case []interface{}:
+                for _, e := range t {
+                    if e, ok := e.(string); ok {
+                        types = append(types, e)
+                    }
+                }
+        }
+        matched := false
+        for _, t := range types {
+            matched = matched || hasJSONType(&c, n, t)
+        }
+        if !matched {
+            bad("expected %s, got %s", strings.Join(types, " or "), valueType(n))
+            return
+        }
+    }
+    if e, ok := schema["enum"].([]interface{}); ok {
+        found := false
+        for _, v := range e {
+            found = found || jsonEqual(&c, n, v)
+        }
+        if !found {
+            bad("%s is not one of the values allowed", n)
+        }
+    }
+    if v, ok := schema["const"]; ok && !jsonEqual(&c, n, v) {
+        bad("%s is not %s", n, jsonText(v))
+    }
+
+    if num, ok := c.numberValue(n); ok && n.Type() != NodeList && n.Type() != NodeMap {
+        x := numberFloat(num)
+        if min, ok := jsonNumber(schema["minimum"]); ok && x < min {
+            bad("%s is less than the minimum %v", n, schema["minimum"])
+        }
+        if max, ok := jsonNumber(schema["maximum"]); ok && x > max {
+            bad("%s is more than the maximum %v", n, schema["maximum"])
+        }
+        if min, ok := jsonNumber(schema["exclusiveMinimum"]); ok && x <= min {
+            bad("%s is not more than %v", n, schema["exclusiveMinimum"])
+        }
+        if max, ok := jsonNumber(schema["exclusiveMaximum"]); ok && x >= max {
+            bad("%s is not less than %v", n, schema["exclusiveMaximum"])
+        }
+        if m, ok := jsonNumber(schema["multipleOf"]); ok && m > 0 {
+            if q := x / m; math.Abs(q-math.Round(q)) > 1e-9 {
+                bad("%s is not a multiple of %v", n, schema["multipleOf"])
+            }
+        }
+    }
+    if text, err := c.stringValue(n, ""); err == nil {
+        length := float64(utf8.RuneCountInString(text))
+        if min, ok := jsonNumber(schema["minLength"]); ok && length < min {
+            bad("%s is shorter than %v characters", n, schema["minLength"])
+        }
+        if max, ok := jsonNumber(schema["maxLength"]); ok && length > max {
+            bad("%s is longer than %v characters", n, schema["maxLength"])
+        }
+        if p, ok := schema["pattern"].(string); ok && !s.regexps[p].MatchString(text) {
+            bad("%s does not match %s", n, p)
+        }
+    }
+
+    switch v := n.(type) {
+        case *ListNode:
+            s.checkList(errs, path, v, schema, depth, bad)
+        case *MapNode:
+            s.checkMap(errs, path, v, schema, depth, bad)
+    }
+
+    if all, ok := schema["allOf"].([]interface{}); ok {
+        for _, sub := range all {
+            s.check(errs, path, n, sub, depth)
+        }
+    }
+    if anyOf, ok := schema["anyOf"].([]interface{}); ok {
+        if s.matches(path, n, anyOf, depth) == 0 {
+            bad("does not match any of the schemas allowed")
+        }
+    }
+    if oneOf, ok := schema["oneOf"].([]interface{}); ok {
+        if m := s.matches(path, n, oneOf, depth); m != 1 {
+            bad("matches %d of the schemas instead of exactly one", m)
+        }
+    }
+    if not, ok := schema["not"]; ok {
+        if s.matches(path, n, []interface{}{not}, depth) == 1 {
+            bad("matches a schema that is not allowed")
+        }
+    }
+}
+
+func (s *JSONSchema) checkList(errs *ValidationErrors, path Path, l *ListNode, schema map[string]interface{}, depth int, bad func(string, ...interface{})) {
+    count := float64(len(l.Nodes))
+    if min, ok := jsonNumber(schema["minItems"]); ok && count < min {
+        bad("has fewer than %v elements", schema["minItems"])
+    }
+    if max, ok := jsonNumber(schema["maxItems"]); ok && count > max {
+        bad("has more than %v elements", schema["maxItems"])
+    }
+    if unique, _ := schema["uniqueItems"].(bool); unique {
+        seen := make(map[string]bool)
+        for _, e := range l.Nodes {
+            key := canonical(e)
+            if seen[key] {
+                bad("has duplicate elements")
+                break
+            }
+            seen[key] = true
+        }
+    }
+    prefix, _ := schema["prefixItems"].([]interface{})
+    for i, e := range l.Nodes {
+        if i < len(prefix) {
+            s.check(errs, path.child(strconv.Itoa(i)), e, prefix[i], depth)
+        } else if items, ok := schema["items"]; ok {
+            s.check(errs, path.child(strconv.Itoa(i)), e, items, depth)
+        }
+    }
+}
+
+func (s *JSONSchema) checkMap(errs *ValidationErrors, path Path, m *MapNode, schema map[string]interface{}, depth int, bad func(string, ...interface{})) {
+    count := float64(len(m.Nodes))
+    if min, ok := jsonNumber(schema["minProperties"]); ok && count < min {
+        bad("has fewer than %v settings", schema["minProperties"])
+    }
+    if max, ok := jsonNumber(schema["maxProperties"]); ok && count > max {
+        bad("has more than %v settings", schema["maxProperties"])
+    }
+    if required, ok := schema["required"].([]interface{}); ok {
+        for _, k := range required {
+            k, _ := k.(string)
+            if _, ok := m.Nodes[k]; !ok {
+                *errs = append(*errs, &ValidationError{Path: path.child(k), Origin: originOf(m), Msg: "missing"})
+            }
+        }
+    }
+    props, _ := schema["properties"].(map[string]interface{})
+    patterns, _ := schema["patternProperties"].(map[string]interface{})
+    additional, hasAdditional := schema["additionalProperties"]
+    for _, k := range m.sortedKeys() {
+        child := m.Nodes[k]
+        matched := false
+        if sub, ok := props[k]; ok {
+            matched = true
+            s.check(errs, path.child(k), child, sub, depth)
+        }
+        for p, sub := range patterns {
+            if s.regexps[p].MatchString(k) {
+                matched = true
+                s.check(errs, path.child(k), child, sub, depth)
+            }
+        }
+        if !matched && hasAdditional {
+            if allowed, ok := additional.(bool); ok && !allowed {
+                *errs = append(*errs, &ValidationError{Path: path.child(k), Origin: originOf(child), Msg: "unknown setting"})
+            } else {
+                s.check(errs, path.child(k), child, additional, depth)
+            }
+        }
+    }
+}
+
+// matches returns the number of schemas that n matches.
+func (s *JSONSchema) matches(path Path, n Node, schemas []interface{}, depth int) int {
+    count := 0
+    for _, sub := range schemas {
+        var errs ValidationErrors
+        s.check(&errs, path, n, sub, depth)
+        if len(errs) == 0 {
+            count++
+        }
+    }
+    return count
+}
+
+// resolveRef returns the schema that ref, a JSON pointer into the
+// document such as #/$defs/port, points to.
+func (s *JSONSchema) resolveRef(ref string) (interface{}, error) {
+    if !strings.HasPrefix(ref, "#") {
+        return nil, fmt.Errorf("$ref %s: only references within the schema are supported", ref)
+    }
+    v := s.root
+    pointer := strings.TrimPrefix(ref, "#")
+    if pointer == "" {
+        return v, nil
+    }
+    for _, tok := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+        tok = strings.Replace(strings.Replace(tok, "~1", "/", -1), "~0", "~", -1)
+        switch cur := v.(type) {
+            case map[string]interface{}:
+                v = cur[tok]
+            case []interface{}:
+                i, err := strconv.Atoi(tok)
+                if err != nil || i < 0 || i >= len(cur) {
+                    return nil, fmt.Errorf("$ref %s: no such schema", ref)
+                }
+                v = cur[i]
+            default:
+                v = nil
+        }
+        if v == nil {
+            return nil, fmt.Errorf("$ref %s: no such schema", ref)
+        }
+    }
+    return v, nil
+}
+
+// hasJSONType reports whether n is of the JSON Schema type t, after the
+// conversions of the getters.
+func hasJSONType(c *Config, n Node, t string) bool {
+    switch t {
+        case "object":
+            return n.Type() == NodeMap
+        case "array":
+            return n.Type() == NodeList
+        case "null":
+            return n.Type() == NodeNil
+        case "boolean":
+            _, err := c.boolValue(n, "")
+            return err == nil
+        case "integer":
+            num, ok := c.numberValue(n)
+            return ok && (num.IsInt || num.BigInt != nil || num.IsFloat && num.Float64 == math.Trunc(num.Float64))
+        case "number":
+            num, ok := c.numberValue(n)
+            return ok && !(num.IsComplex && imag(num.Complex128) != 0)
+        case "string":
+            _, err := c.stringValue(n, "")
+            return err == nil
+    }
+    return false
+}
+
+// jsonEqual reports whether n equals the JSON value v, after the
+// conversions of the getters for scalars.
+func jsonEqual(c *Config, n Node, v interface{}) bool {
+    switch v := v.(type) {
+        case nil:
+            return n.Type() == NodeNil
+        case bool:
+            b, err := c.boolValue(n, "")
+            return err == nil && b == v
+        case json.Number:
+            f, ok := jsonNumber(v)
+            num, isNum := c.numberValue(n)
+            return ok && isNum && numberFloat(num) == f
+        case string:
+            s, err := c.stringValue(n, "")
+            return err == nil && s == v
+    }
+    // Objects and arrays are compared as JSON.
+    var b bytes.Buffer
+    if checkPlain(n, FormatJSON) != nil {
+        return false
+    }
+    writeJSON(&b, n, "")
+    var got interface{}
+    dec := json.NewDecoder(&b)
+    dec.UseNumber()
+    if dec.Decode(&got) != nil {
+        return false
+    }
+    return jsonText(got) == jsonText(v)
+}
+
+// jsonText returns v as compact JSON, with object keys sorted.
+func jsonText(v interface{}) string {
+    b, _ := json.Marshal(v)
+    return string(b)
+}
+
+func jsonNumber(v interface{}) (float64, bool) {
+    n, ok := v.(json.Number)
+    if !ok {
+        return 0, false
+    }
+    f, err := n.Float64()
+    return f, err == nil
+}
+
+func numberFloat(n *NumberNode) float64 {
+    switch {
+        case n.IsInt:
+            return float64(n.Int64)
+        case n.IsUint:
+            return float64(n.Uint64)
+        case n.IsFloat:
+            return n.Float64
+        case n.BigFloat != nil:
+            f, _ := n.BigFloat.Float64()
+            return f
+    }
+    return real(n.Complex128)
+}
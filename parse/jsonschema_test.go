@@ -0,0 +1,186 @@
+package parse
+
+import (
+    "encoding/json"
+    "strings"
+    "testing"
+)
+
+const jsonSchemaReference = `
+server {
+  # The port to listen on.
+  port = 8080
+  host = localhost // the name of the host
+  ratio = 0.5
+  debug = false
+  tags = [a, b]
+  mixed = [1, a]
+  proxy = null
+}
+`
+
+func TestToJSONSchema(t *testing.T) {
+    conf, err := testConfig(t, jsonSchemaReference).Resolve()
+    if err != nil {
+        t.Fatal(err)
+    }
+    out, err := conf.ToJSONSchema(Descriptions("test", jsonSchemaReference))
+    if err != nil {
+        t.Fatal(err)
+    }
+    var doc struct {
+        Schema     string `json:"$schema"`
+        Properties map[string]struct {
+            Type       string
+            Properties map[string]map[string]interface{}
+        }
+    }
+    if err := json.Unmarshal(out, &doc); err != nil {
+        t.Fatalf("%v\n%s", err, out)
+    }
+    if doc.Schema != jsonSchemaDraft {
+        t.Errorf("$schema: got %q", doc.Schema)
+    }
+    server := doc.Properties["server"]
+    expect := map[string]string{
+        "port":  `{"default":8080,"description":"The port to listen on.","type":"integer"}`,
+        "host":  `{"default":"localhost","description":"the name of the host","type":"string"}`,
+        "ratio": `{"default":0.5,"type":"number"}`,
+        "debug": `{"default":false,"type":"boolean"}`,
+        "tags":  `{"default":["a","b"],"items":{"type":"string"},"type":"array"}`,
+        "mixed": `{"default":[1,"a"],"type":"array"}`,
+        "proxy": `{"default":null}`,
+    }
+    if server.Type != "object" || len(server.Properties) != len(expect) {
+        t.Fatalf("server: got %s", out)
+    }
+    for k, v := range expect {
+        if got := jsonText(server.Properties[k]); got != v {
+            t.Errorf("%s: got %s, expected %s", k, got, v)
+        }
+    }
+
+    // A config validates against its own schema.
+    schema, err := ParseJSONSchema(out)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if err := schema.Validate(conf); err != nil {
+        t.Errorf("validating the reference: %v", err)
+    }
+    if _, err := testConfig(t, "a = ${b}").ToJSONSchema(nil); err == nil {
+        t.Errorf("expected an error for an unresolved config")
+    }
+}
+
+func TestToJSONSchemaRoundTrip(t *testing.T) {
+    // The default of a setting named pattern is data, not a regexp.
+    conf, err := testConfig(t, `loggers = [{ pattern = "%d [%t" }]`).Resolve()
+    if err != nil {
+        t.Fatal(err)
+    }
+    out, err := conf.ToJSONSchema(nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+    schema, err := ParseJSONSchema(out)
+    if err != nil {
+        t.Fatalf("%v\n%s", err, out)
+    }
+    if err := schema.Validate(conf); err != nil {
+        t.Errorf("unexpected error: %v", err)
+    }
+}
+
+const jsonSchemaText = `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "type": "object",
+  "required": ["db"],
+  "properties": {
+    "server": {
+      "type": "object",
+      "required": ["port"],
+      "additionalProperties": false,
+      "properties": {
+        "port": {"$ref": "#/$defs/port"},
+        "host": {"type": "string", "pattern": "^[a-z0-9.-]+$", "maxLength": 20},
+        "mode": {"enum": ["dev", "prod"]},
+        "ratio": {"type": "number", "exclusiveMinimum": 0, "multipleOf": 0.25},
+        "tags": {"type": "array", "items": {"type": "string"}, "uniqueItems": true, "maxItems": 3},
+        "pair": {"type": "array", "prefixItems": [{"type": "integer"}, {"type": "boolean"}]},
+        "proxy": {"type": ["string", "null"]},
+        "backend": {"oneOf": [{"type": "integer"}, {"type": "number", "minimum": 10}]}
+      }
+    },
+    "labels": {
+      "type": "object",
+      "patternProperties": {"^x-": {"type": "string", "minLength": 2}},
+      "additionalProperties": {"type": "integer"}
+    }
+  },
+  "$defs": {
+    "port": {"type": "integer", "minimum": 1, "maximum": 65535}
+  }
+}`
+
+func TestJSONSchemaValidate(t *testing.T) {
+    schema, err := ParseJSONSchema([]byte(jsonSchemaText))
+    if err != nil {
+        t.Fatal(err)
+    }
+    conf, err := testConfig(t, `db = {}, server { port = "8080", host = example.com, mode = prod, ratio = 0.75, tags = [a, b], pair = [1, yes], proxy = null }
+labels { x-team = ab, count = 3 }`).Resolve()
+    if err != nil {
+        t.Fatal(err)
+    }
+    if err := schema.Validate(conf); err != nil {
+        t.Errorf("unexpected error: %v", err)
+    }
+
+    conf, err = testConfig(t, `server { port = 0, host = "Example.com", mode = test, ratio = 0.3, tags = [a, a], pair = [1.5, x], backend = 12, extra = 1 }
+labels { x-team = a, count = many }`).Resolve()
+    if err != nil {
+        t.Fatal(err)
+    }
+    err = schema.Validate(conf)
+    errs, ok := err.(ValidationErrors)
+    if !ok {
+        t.Fatalf("expected ValidationErrors, got %v", err)
+    }
+    var got []string
+    for _, e := range errs {
+        got = append(got, e.Error())
+    }
+    expect := []string{
+        "test:1:1: db: missing",
+        "test:2:30: labels.count: expected integer, got string",
+        `test:2:19: labels.x-team: a is shorter than 2 characters`,
+        "test:1:110: server.backend: matches 2 of the schemas instead of exactly one",
+        "test:1:122: server.extra: unknown setting",
+        `test:1:27: server.host: "Example.com" does not match ^[a-z0-9.-]+$`,
+        "test:1:49: server.mode: test is not one of the values allowed",
+        "test:1:91: server.pair.0: expected integer, got number",
+        "test:1:96: server.pair.1: expected boolean, got string",
+        "test:1:17: server.port: 0 is less than the minimum 1",
+        "test:1:63: server.ratio: 0.3 is not a multiple of 0.25",
+        "test:1:75: server.tags: has duplicate elements",
+    }
+    if strings.Join(got, "\n") != strings.Join(expect, "\n") {
+        t.Errorf("got\n\t%s\nexpected\n\t%s", strings.Join(got, "\n\t"), strings.Join(expect, "\n\t"))
+    }
+}
+
+func TestParseJSONSchemaError(t *testing.T) {
+    for _, text := range []string{
+        `{"type": "object"`,
+        `[1]`,
+        `{"pattern": "("}`,
+        `{"patternProperties": {"(": {}}}`,
+        `{"items": {"pattern": "("}}`,
+        `{"$defs": {"a": {"not": {"pattern": "("}}}}`,
+    } {
+        if _, err := ParseJSONSchema([]byte(text)); err == nil {
+            t.Errorf("%s: expected an error", text)
+        }
+    }
+}
@@ -2,6 +2,7 @@ package parse
 
 import (
     "fmt"
+    "math/big"
     "strings"
     "unicode"
     "unicode/utf8"
@@ -68,6 +69,7 @@ type lexer struct {
     lastPos    Pos       // position of most recent item returned by nextItem
     items      chan item // channel of scanned items
     parenDepth int       // nesting depth of ( ) exprs
+    mode       Mode      // parser mode; RecoverErrors keeps scanning after errors
 }
 
 // next returns the next rune in the input.
@@ -135,24 +137,41 @@ func (l *lexer) lineNumber() int {
 
 // errorf returns an error token and terminates the scan by passing
 // back a nil pointer that will be the next state, terminating l.nextItem.
+// In RecoverErrors mode the rest of the line is skipped instead and
+// scanning resumes on the next line.
 func (l *lexer) errorf(format string, args ...interface{}) stateFn {
     l.items <- item{itemError, l.start, fmt.Sprintf(format, args...)}
+    if l.mode&RecoverErrors != 0 {
+        return lexSkipLine
+    }
     return nil
 }
 
-// nextItem returns the next item from the input.
+// nextItem returns the next item from the input. Once the scanner has
+// stopped it keeps returning EOF.
 func (l *lexer) nextItem() item {
-    item := <-l.items
+    item, ok := <-l.items
+    if !ok {
+        item.typ, item.pos = itemEOF, Pos(len(l.input))
+    }
     l.lastPos = item.pos
     return item
 }
 
+// drain drains the output so the lexing goroutine will exit.
+// Called by the parser, not in the lexing goroutine.
+func (l *lexer) drain() {
+    for range l.items {
+    }
+}
+
 // lex creates a new scanner for the input string.
-func lex(name, input string) *lexer {
+func lex(name, input string, mode Mode) *lexer {
     l := &lexer{
         name:       name,
         input:      input,
         items:      make(chan item),
+        mode:       mode,
     }
     go l.run()
     return l
@@ -163,6 +182,7 @@ func (l *lexer) run() {
     for l.state = lexNextToken; l.state != nil; {
         l.state = l.state(l)
     }
+    close(l.items)
 }
 
 // state functions
@@ -181,6 +201,7 @@ func lexNextToken(l *lexer) stateFn {
     switch r := l.next(); {
         case r == eof:
             l.emit(itemEOF)
+            return nil
         case isEndOfLine(r):
             l.emit(itemNewLine)
         case isSpace(r):
@@ -192,7 +213,8 @@ func lexNextToken(l *lexer) stateFn {
             } else if (rr == '*') {
                 return lexComment
             } else {
-                return l.errorf("expected // or /*")
+                l.reset()
+                return lexUnquotedText
             }
         case r == '#':
             return lexDoubleSlashComment
@@ -216,10 +238,12 @@ func lexNextToken(l *lexer) stateFn {
             l.emit(itemCloseSquare)
         case r == '+':
             return lexPlusEquals
+        case r == '$':
+            return lexSubstitution
         case r == '-' || ('0' <= r && r <= '9'):
             l.backup()
             return lexNumber
-        case isAlphaNumeric(r):
+        case isUnquoted(r):
             l.backup()
             return lexUnquotedText
         default:
@@ -232,10 +256,11 @@ func lexNextToken(l *lexer) stateFn {
 func lexComment(l *lexer) stateFn {
     i := strings.Index(l.input[l.pos:], rightComment)
     if i < 0 {
+        l.pos = Pos(len(l.input))
         return l.errorf("unclosed comment")
     }
     l.pos += Pos(i + len(rightComment))
-    l.ignore()
+    l.comment()
     return lexNextToken
 }
 
@@ -244,13 +269,32 @@ func lexDoubleSlashComment(l *lexer) stateFn {
         r := l.next()
         if r == eof || isEndOfLine(r) {
             l.backup()
-            l.ignore()
+            l.comment()
             break
         }
     }
     return lexNextToken
 }
 
+// comment emits the comment just scanned in ParseComments mode and skips
+// it otherwise.
+func (l *lexer) comment() {
+    if l.mode&ParseComments != 0 {
+        l.emit(itemComment)
+    } else {
+        l.ignore()
+    }
+}
+
+// lexSkipLine discards the rest of the line after an error.
+func lexSkipLine(l *lexer) stateFn {
+    for r := l.peek(); r != eof && !isEndOfLine(r); r = l.peek() {
+        l.next()
+    }
+    l.ignore()
+    return lexNextToken
+}
+
 // lexQuote scans a quoted string.
 func lexQuote(l *lexer) stateFn {
     Loop:
@@ -262,6 +306,7 @@ func lexQuote(l *lexer) stateFn {
                 }
                 fallthrough
             case eof, '\n':
+                l.backup()
                 return l.errorf("unterminated quoted string")
             case '"':
                 break Loop
@@ -277,6 +322,7 @@ func lexRawQuote(l *lexer) stateFn {
     for {
         switch l.next() {
             case eof, '\n':
+                l.backup()
                 return l.errorf("unterminated raw quoted string")
             case '`':
              break Loop
@@ -296,20 +342,22 @@ func lexSpace(l *lexer) stateFn {
     return lexNextToken
 }
 
-// lexUnquotedText scans an alphanumeric.
+// lexUnquotedText scans an unquoted string: a run of any characters but
+// whitespace and the ones HOCON reserves, ending before a "//" comment.
 func lexUnquotedText(l *lexer) stateFn {
     Loop:
     for {
         switch r := l.next(); {
-            case isAlphaNumeric(r), r == '.':
+            case isUnquoted(r) && !(r == '/' && l.peek() == '/'):
                 // absorb.
             default:
                 l.backup()
-                word := l.input[l.start:l.pos]
-                switch {
-                    case word == "true", word == "false", word == "on", word == "off":
+                // Keywords are only recognized as whole words, so
+                // "nullable" or "yesterday" remain unquoted text.
+                switch l.input[l.start:l.pos] {
+                    case "true", "false", "yes", "no", "on", "off":
                         l.emit(itemBool)
-                    case word == "nil":
+                    case "null":
                         l.emit(itemNull)
                     default:
                         l.emit(itemUnquotedText)
@@ -321,17 +369,22 @@ func lexUnquotedText(l *lexer) stateFn {
 }
 
 // lexNumber scans a number: decimal, octal, hex, float, or imaginary. This
-// isn't a perfect number scanner - for instance it accepts "." and "0x0.2"
-// and "089" - but when it's wrong the input is invalid and the parser (via
-// strconv) will notice.
+// isn't a perfect number scanner - for instance it accepts "0x0.2" and
+// "089" - but when it's wrong the input is invalid and the parser (via
+// strconv) will notice. Text that starts like a number but is not one,
+// such as "-" or "2014-01-01", is scanned as unquoted text instead.
 func lexNumber(l *lexer) stateFn {
-    if !l.scanNumber() {
-        return l.errorf("bad number syntax: %q", l.input[l.start:l.pos])
+    if !l.scanNumber() || !isNumber(l.input[l.start:l.pos]) {
+        l.reset()
+        return lexUnquotedText
     }
     if sign := l.peek(); sign == '+' || sign == '-' {
         // Complex: 1+2i. No spaces, must end in 'i'.
-        if !l.scanNumber() || l.input[l.pos-1] != 'i' {
-            return l.errorf("bad number syntax: %q", l.input[l.start:l.pos])
+        end := l.pos
+        if !l.scanNumber() || l.input[l.pos-1] != 'i' || !isNumber(l.input[end:l.pos]) {
+            l.pos = end
+            l.emit(itemNumber)
+            return lexNextToken
         }
         l.emit(itemComplex)
     } else {
@@ -340,6 +393,16 @@ func lexNumber(l *lexer) stateFn {
     return lexNextToken
 }
 
+// isNumber reports whether s, as scanned by scanNumber, is a valid number.
+func isNumber(s string) bool {
+    s = strings.TrimSuffix(s, "i")
+    if _, ok := new(big.Int).SetString(s, 0); ok {
+        return true
+    }
+    _, _, err := big.ParseFloat(s, 0, 64, big.ToNearestEven)
+    return err == nil
+}
+
 func (l *lexer) scanNumber() bool {
     // Optional leading sign.
     l.accept("+-")
@@ -362,6 +425,24 @@ func (l *lexer) scanNumber() bool {
     return true
 }
 
+// lexSubstitution scans a substitution, ${path} or ${?path}. The dollar
+// sign is known to be present.
+func lexSubstitution(l *lexer) stateFn {
+    if l.next() != '{' {
+        return l.errorf("expected { after $")
+    }
+    for {
+        switch l.next() {
+            case eof, '\n':
+                l.backup()
+                return l.errorf("unterminated substitution")
+            case '}':
+                l.emit(itemSubStitution)
+                return lexNextToken
+        }
+    }
+}
+
 // lexPlusEquals scans +=
 func lexPlusEquals(l *lexer) stateFn {
     if r := l.next(); r == '=' {
@@ -385,4 +466,10 @@ func isEndOfLine(r rune) bool {
 // isAlphaNumeric reports whether r is an alphabetic, digit, or underscore.
 func isAlphaNumeric(r rune) bool {
     return r == '_' || r == '-' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// isUnquoted reports whether r may appear in an unquoted string: anything
+// but whitespace and the characters HOCON forbids there.
+func isUnquoted(r rune) bool {
+    return r != eof && !unicode.IsSpace(r) && !strings.ContainsRune("$\"{}[]:=,+#`^?!@*&\\", r)
 }
\ No newline at end of file
@@ -48,12 +48,26 @@ var lexTests = []lexTest{
     {"number", "a=-1.2", []item{{itemUnquotedText, 0, "a"}, {itemEquals, 0, "="}, {itemNumber, 0, "-1.2"}, tEOF}},
     {"unquote", "a=-1.2 min", []item{{itemUnquotedText, 0, "a"}, {itemEquals, 0, "="}, {itemNumber, 0, "-1.2"}, {itemSpace, 0, " "}, {itemUnquotedText, 0, "min"}, tEOF}},
     {"true", "a=true", []item{{itemUnquotedText, 0, "a"}, {itemEquals, 0, "="}, {itemBool, 0, "true"}, tEOF}},
-    {"nil", "a=nil", []item{{itemUnquotedText, 0, "a"}, {itemEquals, 0, "="}, {itemNull, 0, "nil"}, tEOF}},
+    {"null", "a=null", []item{{itemUnquotedText, 0, "a"}, {itemEquals, 0, "="}, {itemNull, 0, "null"}, tEOF}},
+    {"nil", "a=nil", []item{{itemUnquotedText, 0, "a"}, {itemEquals, 0, "="}, {itemUnquotedText, 0, "nil"}, tEOF}},
+    {"keyword prefix", "nullable truest yesterday onward", []item{{itemUnquotedText, 0, "nullable"}, {itemSpace, 0, " "},
+        {itemUnquotedText, 0, "truest"}, {itemSpace, 0, " "}, {itemUnquotedText, 0, "yesterday"}, {itemSpace, 0, " "}, {itemUnquotedText, 0, "onward"}, tEOF}},
+    {"booleans", "yes no on off", []item{{itemBool, 0, "yes"}, {itemSpace, 0, " "}, {itemBool, 0, "no"}, {itemSpace, 0, " "},
+        {itemBool, 0, "on"}, {itemSpace, 0, " "}, {itemBool, 0, "off"}, tEOF}},
+    {"unquoted path", "foo/bar.baz", []item{{itemUnquotedText, 0, "foo/bar.baz"}, tEOF}},
+    {"unquoted comment", "foo//bar", []item{{itemUnquotedText, 0, "foo"}, tEOF}},
+    {"address", "127.0.0.1", []item{{itemNumber, 0, "127.0"}, {itemUnquotedText, 0, ".0.1"}, tEOF}},
+    {"unit", "10s", []item{{itemNumber, 0, "10"}, {itemUnquotedText, 0, "s"}, tEOF}},
+    {"date", "2014-01-31", []item{{itemNumber, 0, "2014"}, {itemNumber, 0, "-01"}, {itemNumber, 0, "-31"}, tEOF}},
+    {"dash", "- -x", []item{{itemUnquotedText, 0, "-"}, {itemSpace, 0, " "}, {itemUnquotedText, 0, "-x"}, tEOF}},
+    {"substitution", "a=${b.c} ${?d}", []item{{itemUnquotedText, 0, "a"}, {itemEquals, 0, "="}, {itemSubStitution, 0, "${b.c}"}, {itemSpace, 0, " "}, {itemSubStitution, 0, "${?d}"}, tEOF}},
+    {"unterminated substitution", "${a\n", []item{{itemError, 0, "unterminated substitution"}}},
+    {"forbidden", "a@b", []item{{itemUnquotedText, 0, "a"}, {itemError, 0, "unrecognized character in action: U+0040 '@'"}}},
 }
 
 // collect gathers the emitted items into a slice.
 func collect(t *lexTest) (items []item) {
-    l := lex(t.name, t.input)
+    l := lex(t.name, t.input, 0)
     for {
         item := l.nextItem()
         items = append(items, item)
@@ -3,6 +3,9 @@ package parse
 import (
     "bytes"
     "fmt"
+    "math"
+    "math/big"
+    "sort"
     "strconv"
     "strings"
 )
@@ -48,10 +51,12 @@ const (
     NodeField                      // A field or method name.
     NodeList                       // A list of Nodes.
     NodeMap                        // A map of Nodes.
-    NodeNil                        // An untyped nil constant.
+    NodeNil                        // The null constant.
     NodeBool                       // A boolean constant.
     NodeNumber                     // A numerical constant.
     NodeString                     // A string constant.
+    NodeSubstitution               // A ${path} reference to another value.
+    NodeConcat                     // A concatenation of values including substitutions.
 )
 
 // Nodes.
@@ -78,12 +83,22 @@ func (m *MapNode) tree() *Tree {
 
 func (m *MapNode) String() string {
     b := new(bytes.Buffer)
-    for k, n := range m.Nodes {
-        fmt.Fprint(b, k, " = (", n, ")")
+    for _, k := range m.sortedKeys() {
+        fmt.Fprint(b, k, " = (", m.Nodes[k], ")")
     }
     return b.String()
 }
 
+// sortedKeys returns the keys of the map in sorted order.
+func (m *MapNode) sortedKeys() []string {
+    keys := make([]string, 0, len(m.Nodes))
+    for k := range m.Nodes {
+        keys = append(keys, k)
+    }
+    sort.Strings(keys)
+    return keys
+}
+
 func (m *MapNode) CopyMap() *MapNode {
     if m == nil {
         return m
@@ -99,15 +114,26 @@ func (m *MapNode) Copy() Node {
     return m.CopyMap()
 }
 
+// withFallback returns a new object with the keys of m and those of other
+// that m lacks; objects under the same key are merged likewise. Neither m
+// nor other is changed, and the result shares their children.
 func (m *MapNode) withFallback(other Node) Node {
-    if o, ok := other.(*MapNode); ok {
-        for k, v := range o.Nodes {
-            if _, ok := m.Nodes[k]; !ok {
-                m.Nodes[k] = v
-            } else {
-                m.Nodes[k] = m.Nodes[k].withFallback(v)
+    switch o := other.(type) {
+        case *MapNode:
+            merged := m.tr.newMap(m.Pos)
+            for k, v := range o.Nodes {
+                merged.Nodes[k] = v
             }
-        }
+            for k, v := range m.Nodes {
+                if fallback, ok := o.Nodes[k]; ok {
+                    v = v.withFallback(fallback)
+                }
+                merged.Nodes[k] = v
+            }
+            return merged
+        case *SubstitutionNode, *ConcatNode:
+            // The fallback is only known once resolved: merge then.
+            return m.tr.newConcat(m.Pos, []Node{other, m}, []string{"", ""})
     }
     return m
 }
@@ -187,7 +213,7 @@ func (m *TextNode) withFallback(other Node) Node {
     return m
 }
 
-// NilNode holds the special identifier 'nil' representing an untyped nil constant.
+// NilNode holds the keyword 'null' representing the null value.
 type NilNode struct {
     NodeType
     Pos
@@ -206,7 +232,7 @@ func (n *NilNode) Type() NodeType {
 }
 
 func (n *NilNode) String() string {
-    return "nil"
+    return "null"
 }
 
 func (n *NilNode) tree() *Tree {
@@ -259,8 +285,9 @@ func (m *FieldNode) withFallback(other Node) Node {
 type BoolNode struct {
     NodeType
     Pos
-    tr   *Tree
-    True bool // The value of the boolean constant.
+    tr     *Tree
+    True   bool // The value of the boolean constant.
+    Secret bool // The value is secret: String and the renderers redact it.
 }
 
 func (t *Tree) newBool(pos Pos, true bool) *BoolNode {
@@ -268,6 +295,9 @@ func (t *Tree) newBool(pos Pos, true bool) *BoolNode {
 }
 
 func (b *BoolNode) String() string {
+    if b.Secret {
+        return Redacted
+    }
     if b.True {
         return "true"
     }
@@ -279,7 +309,9 @@ func (b *BoolNode) tree() *Tree {
 }
 
 func (b *BoolNode) Copy() Node {
-    return b.tr.newBool(b.Pos, b.True)
+    c := b.tr.newBool(b.Pos, b.True)
+    c.Secret = b.Secret
+    return c
 }
 
 func (m *BoolNode) withFallback(other Node) Node {
@@ -289,6 +321,8 @@ func (m *BoolNode) withFallback(other Node) Node {
 // NumberNode holds a number: signed or unsigned integer, float, or complex.
 // The value is parsed and stored under all the types that can represent the value.
 // This simulates in a small amount of code the behavior of Go's ideal constants.
+// Real numbers are also kept with arbitrary precision, so that those beyond
+// the range of float64 have only BigFloat; the exact decimal value is Text.
 type NumberNode struct {
     NodeType
     Pos
@@ -301,11 +335,25 @@ type NumberNode struct {
     Uint64     uint64     // The unsigned integer value.
     Float64    float64    // The floating-point value.
     Complex128 complex128 // The complex value.
+    BigInt     *big.Int   // The exact integer value, or nil if not an integer.
+    BigFloat   *big.Float // The value with enough precision for Text, or nil if complex.
     Text       string     // The original textual representation from the input.
+    Secret     bool       // The value is secret: String and the renderers redact it.
 }
 
 func (t *Tree) newNumber(pos Pos, text string, typ itemType) (*NumberNode, error) {
-    n := &NumberNode{tr: t, NodeType: NodeNumber, Pos: pos, Text: text}
+    n, err := parseNumber(pos, text, typ)
+    if err != nil {
+        return nil, err
+    }
+    n.tr = t
+    return n, nil
+}
+
+// parseNumber returns the number text, a token of type typ, outside of
+// any tree.
+func parseNumber(pos Pos, text string, typ itemType) (*NumberNode, error) {
+    n := &NumberNode{NodeType: NodeNumber, Pos: pos, Text: text}
     switch typ {
         case itemComplex:
             // fmt.Sscan can parse the pair, so let it do the work.
@@ -364,12 +412,38 @@ func (t *Tree) newNumber(pos Pos, text string, typ itemType) (*NumberNode, error
             }
         }
     }
-    if !n.IsInt && !n.IsUint && !n.IsFloat {
+    n.setBig(text)
+    // Numbers beyond the range of float64 have only their big value.
+    if !n.IsInt && !n.IsUint && !n.IsFloat && n.BigFloat == nil {
         return nil, fmt.Errorf("illegal number syntax: %q", text)
     }
     return n, nil
 }
 
+// setBig stores the arbitrary precision value of text, and the float value
+// of integers too large for the fixed size types.
+func (n *NumberNode) setBig(text string) {
+    if i, ok := new(big.Int).SetString(text, 0); ok {
+        n.BigInt = i
+    }
+    // About 3.3 bits per decimal digit; 4 per character is plenty.
+    prec := uint(4*len(text) + 64)
+    if f, _, err := big.ParseFloat(text, 0, prec, big.ToNearestEven); err == nil {
+        n.BigFloat = f
+        if n.BigInt == nil && f.IsInt() && f.Acc() == big.Exact {
+            n.BigInt, _ = f.Int(nil)
+        }
+    } else if n.BigInt != nil {
+        n.BigFloat = new(big.Float).SetPrec(prec).SetInt(n.BigInt)
+    }
+    if !n.IsFloat && n.BigFloat != nil {
+        if f, _ := n.BigFloat.Float64(); !math.IsInf(f, 0) {
+            n.IsFloat = true
+            n.Float64 = f
+        }
+    }
+}
+
 // simplifyComplex pulls out any other types that are represented by the complex number.
 // These all require that the imaginary part be zero.
 func (n *NumberNode) simplifyComplex() {
@@ -388,6 +462,9 @@ func (n *NumberNode) simplifyComplex() {
 }
 
 func (n *NumberNode) String() string {
+    if n.Secret {
+        return Redacted
+    }
     return n.Text
 }
 
@@ -398,6 +475,12 @@ func (n *NumberNode) tree() *Tree {
 func (n *NumberNode) Copy() Node {
     nn := new(NumberNode)
     *nn = *n // Easy, fast, correct.
+    if n.BigInt != nil {
+        nn.BigInt = new(big.Int).Set(n.BigInt)
+    }
+    if n.BigFloat != nil {
+        nn.BigFloat = new(big.Float).Copy(n.BigFloat)
+    }
     return nn
 }
 
@@ -412,6 +495,7 @@ type StringNode struct {
     tr     *Tree
     Quoted string // The original text of the string, with quotes.
     Text   string // The string, after quote processing.
+    Secret bool   // The value is secret: String and the renderers redact it.
 }
 
 func (t *Tree) newString(pos Pos, orig, text string) *StringNode {
@@ -419,6 +503,9 @@ func (t *Tree) newString(pos Pos, orig, text string) *StringNode {
 }
 
 func (s *StringNode) String() string {
+    if s.Secret {
+        return Redacted
+    }
     return s.Quoted
 }
 
@@ -427,9 +514,124 @@ func (s *StringNode) tree() *Tree {
 }
 
 func (s *StringNode) Copy() Node {
-    return s.tr.newString(s.Pos, s.Quoted, s.Text)
+    c := s.tr.newString(s.Pos, s.Quoted, s.Text)
+    c.Secret = s.Secret
+    return c
 }
 
 func (m *StringNode) withFallback(other Node) Node {
     return m
-}
\ No newline at end of file
+}
+// SubstitutionNode holds a reference to another value of the config,
+// ${path}, or ${?path} if it is optional. It is replaced by that value
+// when the config is resolved.
+type SubstitutionNode struct {
+    NodeType
+    Pos
+    tr       *Tree
+    Path     string // The path of the referenced value.
+    Optional bool   // The substitution is ${?path}.
+    Text     string // The original text, ${path} or ${?path}.
+    Secret   bool   // The value substituted is secret.
+    Fallback Node   // The earlier value of the field, or nil: see withFallback.
+}
+
+func (t *Tree) newSubstitution(pos Pos, text string) *SubstitutionNode {
+    path := text[2 : len(text)-1]
+    optional := strings.HasPrefix(path, "?")
+    if optional {
+        path = path[1:]
+    }
+    return &SubstitutionNode{tr: t, NodeType: NodeSubstitution, Pos: pos, Path: strings.TrimSpace(path), Optional: optional, Text: text}
+}
+
+func (s *SubstitutionNode) String() string {
+    return s.Text
+}
+
+func (s *SubstitutionNode) tree() *Tree {
+    return s.tr
+}
+
+func (s *SubstitutionNode) Copy() Node {
+    c := s.tr.newSubstitution(s.Pos, s.Text)
+    c.Secret = s.Secret
+    if s.Fallback != nil {
+        c.Fallback = s.Fallback.Copy()
+    }
+    return c
+}
+
+// withFallback returns a copy of m that keeps other as its fallback: if
+// m resolves to an object, the object is merged over other, as resolved,
+// if that is an object too; if m is optional and missing, other is the
+// value. Otherwise m replaces other.
+func (m *SubstitutionNode) withFallback(other Node) Node {
+    c := m.tr.newSubstitution(m.Pos, m.Text)
+    c.Secret = m.Secret
+    c.Fallback = pendingFallback(m.Fallback, other)
+    return c
+}
+
+// pendingFallback returns other as the fallback of a value whose own
+// fallback, if any, is fallback.
+func pendingFallback(fallback, other Node) Node {
+    if fallback != nil {
+        return fallback.withFallback(other)
+    }
+    return other
+}
+
+// ConcatNode holds a value concatenation that includes substitutions, so
+// that the values can only be joined once the config is resolved.
+type ConcatNode struct {
+    NodeType
+    Pos
+    tr     *Tree
+    Nodes  []Node   // The concatenated values in lexical order.
+    Spaces []string // The whitespace before each value.
+    Secret bool     // The value is secret: String and the renderers redact it.
+    // The earlier value of the field, or nil: see withFallback.
+    Fallback Node
+}
+
+func (t *Tree) newConcat(pos Pos, nodes []Node, spaces []string) *ConcatNode {
+    return &ConcatNode{tr: t, NodeType: NodeConcat, Pos: pos, Nodes: nodes, Spaces: spaces}
+}
+
+func (c *ConcatNode) String() string {
+    if c.Secret {
+        return Redacted
+    }
+    b := new(bytes.Buffer)
+    for i, n := range c.Nodes {
+        fmt.Fprint(b, c.Spaces[i], n)
+    }
+    return b.String()
+}
+
+func (c *ConcatNode) tree() *Tree {
+    return c.tr
+}
+
+func (c *ConcatNode) Copy() Node {
+    nodes := make([]Node, len(c.Nodes))
+    for i, n := range c.Nodes {
+        nodes[i] = n.Copy()
+    }
+    n := c.tr.newConcat(c.Pos, nodes, append([]string{}, c.Spaces...))
+    n.Secret = c.Secret
+    if c.Fallback != nil {
+        n.Fallback = c.Fallback.Copy()
+    }
+    return n
+}
+
+// withFallback returns a copy of m that keeps other as its fallback, as
+// SubstitutionNode.withFallback does. The copy shares the values of m.
+func (m *ConcatNode) withFallback(other Node) Node {
+    c := m.tr.newConcat(m.Pos, m.Nodes, m.Spaces)
+    c.Secret = m.Secret
+    c.Fallback = pendingFallback(m.Fallback, other)
+    return c
+}
@@ -0,0 +1,155 @@
+package parse
+
+import (
+    "fmt"
+    "math/big"
+)
+
+// Root returns the node c wraps. It is shared with c and any config it was
+// taken from, and must not be changed.
+func (c *Config) Root() Node {
+    return c.root
+}
+
+// Origin is where a value was defined: the name given to the parser and
+// the line and column of the value in its input.
+type Origin struct {
+    Name string
+    Line int // 1-based; zero if unknown
+    Col  int // 1-based, in bytes
+}
+
+func (o Origin) String() string {
+    if o.Line == 0 {
+        return o.Name
+    }
+    return fmt.Sprintf("%s:%d:%d", o.Name, o.Line, o.Col)
+}
+
+// Origin returns where the value of c was defined. Values built by the
+// library rather than parsed, such as those taken from the environment,
+// report the place of the expression that produced them.
+func (c *Config) Origin() Origin {
+    if c.root == nil {
+        return Origin{}
+    }
+    return originOf(c.root)
+}
+
+func originOf(n Node) Origin {
+    t := n.tree()
+    if t == nil {
+        return Origin{}
+    }
+    line, col := t.location(n.Position())
+    return Origin{Name: t.ParseName, Line: line, Col: col}
+}
+
+// Keys returns the keys of c in sorted order, or nil if c is not an object.
+func (c *Config) Keys() []string {
+    m, ok := c.root.(*MapNode)
+    if !ok {
+        return nil
+    }
+    return m.sortedKeys()
+}
+
+// Get returns the value of key in c. Unlike GetValue, key is a single key
+// and is not split at dots. The result is false if c is not an object or
+// has no such key.
+func (c *Config) Get(key string) (conf *Config, ok bool) {
+    m, isMap := c.root.(*MapNode)
+    if !isMap {
+        return
+    }
+    n, ok := m.Nodes[key]
+    if ok {
+        conf = c.wrap(n)
+    }
+    return
+}
+
+// Len returns the number of keys of an object or elements of a list, and
+// zero for any other value.
+func (c *Config) Len() int {
+    switch n := c.root.(type) {
+        case *MapNode:
+            return len(n.Nodes)
+        case *ListNode:
+            return len(n.Nodes)
+    }
+    return 0
+}
+
+// ToMap returns the object c as a map of its unwrapped values, or nil if c
+// is not an object.
+func (c *Config) ToMap() map[string]interface{} {
+    m, ok := c.root.(*MapNode)
+    if !ok {
+        return nil
+    }
+    return unwrap(m).(map[string]interface{})
+}
+
+// Unwrapped returns c as plain Go values: objects become
+// map[string]interface{}, lists []interface{}, strings string, booleans
+// bool and null nil. Integers become int64, or uint64 or *big.Int when too
+// large; other numbers become float64, or complex128. Unresolved
+// substitutions are left as their text.
+func (c *Config) Unwrapped() interface{} {
+    if c.root == nil {
+        return nil
+    }
+    return unwrap(c.root)
+}
+
+func unwrap(n Node) interface{} {
+    switch v := n.(type) {
+        case *MapNode:
+            m := make(map[string]interface{}, len(v.Nodes))
+            for k, child := range v.Nodes {
+                m[k] = unwrap(child)
+            }
+            return m
+        case *ListNode:
+            l := make([]interface{}, len(v.Nodes))
+            for i, elem := range v.Nodes {
+                l[i] = unwrap(elem)
+            }
+            return l
+        case *StringNode:
+            return v.Text
+        case *TextNode:
+            return string(v.Text)
+        case *BoolNode:
+            return v.True
+        case *NilNode:
+            return nil
+        case *NumberNode:
+            return unwrapNumber(v)
+    }
+    return n.String()
+}
+
+func unwrapNumber(n *NumberNode) interface{} {
+    if n.IsComplex && imag(n.Complex128) != 0 {
+        return n.Complex128
+    }
+    // Keep integers written as such apart from floats with integral values.
+    if _, ok := new(big.Int).SetString(n.Text, 0); ok {
+        switch {
+            case n.IsInt:
+                return n.Int64
+            case n.IsUint:
+                return n.Uint64
+        }
+        return new(big.Int).Set(n.BigInt)
+    }
+    if n.IsFloat {
+        return n.Float64
+    }
+    if n.BigFloat != nil {
+        return new(big.Float).Copy(n.BigFloat)
+    }
+    return n.Text
+}
@@ -0,0 +1,52 @@
+package parse
+
+import (
+    "math/big"
+    "reflect"
+    "strings"
+    "testing"
+)
+
+func TestObjectView(t *testing.T) {
+    conf := testConfig(t, configInput)
+    cluster, err := conf.GetValue("akka.cluster")
+    if err != nil {
+        t.Fatal(err)
+    }
+    if keys := strings.Join(cluster.Keys(), " "); keys != "roles seed-nodes" {
+        t.Errorf("got keys %q, expected %q", keys, "roles seed-nodes")
+    }
+    if cluster.Len() != 2 || cluster.Root().Type() != NodeMap {
+        t.Errorf("got %d keys of a %v, expected 2 of a map", cluster.Len(), cluster.Root().Type())
+    }
+    akka, _ := conf.Get("akka")
+    if v, ok := akka.Get("a.b"); !ok || v.String() != "dotted" {
+        t.Errorf(`Get("a.b"): got %v, %v, expected dotted`, v, ok)
+    }
+    if _, ok := conf.Get("akka.loglevel"); ok {
+        t.Errorf("Get split its key at dots")
+    }
+    roles, _ := cluster.Get("roles")
+    if roles.Keys() != nil || roles.ToMap() != nil || roles.Len() != 2 {
+        t.Errorf("list seen as an object: %v, %v, %d", roles.Keys(), roles.ToMap(), roles.Len())
+    }
+    if _, ok := roles.Get("0"); ok {
+        t.Errorf("Get found a list element")
+    }
+}
+
+func TestUnwrapped(t *testing.T) {
+    conf := testConfig(t, `a { s = "x", t = y z, b = on, n = null }
+        l = [1, 1.0, -2, 18446744073709551615, 1e3, 100000000000000000000000, 2i]`)
+    huge, _ := new(big.Int).SetString("100000000000000000000000", 10)
+    expect := map[string]interface{}{
+        "a": map[string]interface{}{"s": "x", "t": "y z", "b": true, "n": nil},
+        "l": []interface{}{int64(1), 1.0, int64(-2), uint64(18446744073709551615), 1e3, huge, 2i},
+    }
+    if got := conf.ToMap(); !reflect.DeepEqual(got, expect) {
+        t.Errorf("got\n\t%#v\nexpected\n\t%#v", got, expect)
+    }
+    if got := conf.Unwrapped(); !reflect.DeepEqual(got, expect) {
+        t.Errorf("got\n\t%#v\nexpected\n\t%#v", got, expect)
+    }
+}
@@ -0,0 +1,271 @@
+package parse
+
+import (
+    "bytes"
+    "sort"
+    "strconv"
+    "strings"
+)
+
+// Symbol is a field of an object in HOCON text, as listed by Outline.
+type Symbol struct {
+    Key      string    // the key as written, such as a.b or "x.y"
+    Doc      string    // the comments above the field, or else after it
+    Path     Path      // the path of the field from the root
+    Pos      Pos       // the start of the key
+    KeyEnd   Pos       // the end of the key
+    End      Pos       // the end of the value
+    Object   bool      // the value is an object in braces
+    Children []*Symbol // the fields of an object value
+}
+
+// Reference is a substitution or an include statement in HOCON text, as
+// listed by Outline.
+type Reference struct {
+    Pos     Pos
+    End     Pos
+    Path    Path   // the path referred to by a substitution
+    Include string // the file named by an include statement
+}
+
+// Outline lists the fields of the HOCON text, with objects in lists named
+// by their index, and the substitutions and include statements in it, in
+// the order they appear. Unlike Parse it goes by the tokens alone, for
+// tools that need to know where things are written: it lists every field,
+// also those that are overridden later, and does its best with broken
+// text.
+func Outline(name, text string) ([]*Symbol, []*Reference) {
+    nodes := groupTokens(lexAll(name, text), new(int))
+    var refs []*Reference
+    symbols := outlineFields(nodes, Path{}, &refs)
+    refs = substitutions(nodes, refs)
+    sort.SliceStable(refs, func(i, j int) bool {
+        return refs[i].Pos < refs[j].Pos
+    })
+    return symbols, refs
+}
+
+// outlineFields returns the symbols of the fields in the contents of an
+// object at path, and adds its include statements to refs.
+func outlineFields(nodes []*fmtNode, path Path, refs *[]*Reference) []*Symbol {
+    var symbols []*Symbol
+    head, fields, _ := splitFields(nodes)
+    if len(path) == 0 && len(fields) > 0 {
+        // At the top there is no opening brace for the comments of the
+        // head to end the line of: they are about the first field.
+        fields[0].nodes = append(head, fields[0].nodes...)
+    }
+    for _, f := range fields {
+        var code []*fmtNode
+        var above, after []string
+        for _, n := range f.nodes {
+            switch {
+                case !n.is(itemComment):
+                    code = append(code, n)
+                case nextCode(code, 0) == len(code):
+                    above = append(above, commentText(n.open.val))
+                default:
+                    after = append(after, commentText(n.open.val))
+            }
+        }
+        first := nextCode(code, 0)
+        switch {
+            case first == len(code):
+                continue
+            case f.include:
+                for _, n := range code {
+                    if n.is(itemString) {
+                        *refs = append(*refs, &Reference{Pos: n.open.pos, End: n.end(), Include: unquoteToken(n.open)})
+                        break
+                    }
+                }
+                continue
+            case isObject(code[first]):
+                // Braces around the root object.
+                symbols = append(symbols, outlineFields(code[first].children, path, refs)...)
+                continue
+        }
+        // The key runs up to the separator or the object value.
+        i := first
+        for i < len(code) && !isSeparator(code[i]) && !code[i].group {
+            i++
+        }
+        keyEnd := i
+        for keyEnd > first && isBlank(code[keyEnd-1]) {
+            keyEnd--
+        }
+        if keyEnd == first {
+            continue
+        }
+        last := len(code)
+        for last > i && isBlank(code[last-1]) {
+            last--
+        }
+        doc := above
+        if len(doc) == 0 {
+            doc = after
+        }
+        s := &Symbol{
+            Key:    keyText(code[first:keyEnd]),
+            Doc:    strings.TrimSpace(strings.Join(doc, "\n")),
+            Pos:    code[first].open.pos,
+            KeyEnd: code[keyEnd-1].end(),
+            End:    code[last-1].end(),
+        }
+        s.Path = append(append(Path{}, path...), keySegments(code[first:keyEnd])...)
+        value := nextCode(code, i)
+        if value < len(code) && isSeparator(code[value]) {
+            value = nextCode(code, value+1)
+        }
+        if value < len(code) && isObject(code[value]) {
+            s.Object = true
+            s.Children = outlineFields(code[value].children, s.Path, refs)
+        } else {
+            for _, n := range code[value:] {
+                if n.isList() {
+                    s.Children = append(s.Children, outlineList(n, s.Path, refs)...)
+                }
+            }
+        }
+        symbols = append(symbols, s)
+    }
+    return symbols
+}
+
+// outlineList returns the symbols of the objects in the list n at path.
+func outlineList(n *fmtNode, path Path, refs *[]*Reference) []*Symbol {
+    var symbols []*Symbol
+    index, started := 0, false
+    for _, c := range n.children {
+        switch {
+            case c.is(itemComma) || c.is(itemNewLine):
+                if started {
+                    index++
+                }
+                started = false
+                continue
+            case isBlank(c) || c.is(itemComment):
+                continue
+        }
+        started = true
+        elem := path.child(strconv.Itoa(index))
+        if isObject(c) {
+            symbols = append(symbols, outlineFields(c.children, elem, refs)...)
+        } else if c.isList() {
+            symbols = append(symbols, outlineList(c, elem, refs)...)
+        }
+    }
+    return symbols
+}
+
+// substitutions adds the substitutions in nodes and below to refs.
+func substitutions(nodes []*fmtNode, refs []*Reference) []*Reference {
+    for _, n := range nodes {
+        if n.group {
+            refs = substitutions(n.children, refs)
+            continue
+        }
+        if n.open.typ != itemSubStitution {
+            continue
+        }
+        text := strings.TrimPrefix(n.open.val[2:len(n.open.val)-1], "?")
+        if segs, err := parsePath(strings.TrimSpace(text)); err == nil {
+            refs = append(refs, &Reference{Pos: n.open.pos, End: n.end(), Path: Path(segs)})
+        }
+    }
+    return refs
+}
+
+// commentText returns the text of a comment without its markers and the
+// space after them.
+func commentText(c string) string {
+    if strings.HasPrefix(c, leftComment) {
+        c = strings.TrimSuffix(strings.TrimPrefix(c, leftComment), rightComment)
+        lines := strings.Split(c, "\n")
+        for i, l := range lines {
+            lines[i] = strings.TrimPrefix(strings.TrimSpace(l), "* ")
+        }
+        return strings.TrimSpace(strings.Join(lines, "\n"))
+    }
+    c = strings.TrimPrefix(strings.TrimPrefix(c, "#"), doubleSlashComment)
+    return strings.TrimSpace(c)
+}
+
+// Descriptions returns the comments documenting the fields of the HOCON
+// text, as in Symbol.Doc, by the path of the field as given by
+// Path.String. Of a field written more than once, the last comments are
+// kept.
+func Descriptions(name, text string) map[string]string {
+    docs := make(map[string]string)
+    var add func(symbols []*Symbol)
+    add = func(symbols []*Symbol) {
+        for _, s := range symbols {
+            if s.Doc != "" {
+                docs[s.Path.String()] = s.Doc
+            }
+            add(s.Children)
+        }
+    }
+    symbols, _ := Outline(name, text)
+    add(symbols)
+    return docs
+}
+
+// end returns the position after the token or group n.
+func (n *fmtNode) end() Pos {
+    if n.group {
+        if n.close.val != "" {
+            return n.close.pos + Pos(len(n.close.val))
+        }
+        if len(n.children) > 0 {
+            return n.children[len(n.children)-1].end()
+        }
+        return n.open.pos + 1
+    }
+    return n.open.pos + Pos(len(n.open.val))
+}
+
+// keyText returns the key made of the tokens nodes as written.
+func keyText(nodes []*fmtNode) string {
+    var b bytes.Buffer
+    for _, n := range nodes {
+        b.WriteString(n.open.val)
+    }
+    return b.String()
+}
+
+// keySegments splits the key made of the tokens nodes into its path
+// segments, as parseKey does.
+func keySegments(nodes []*fmtNode) []string {
+    segs := []string{""}
+    space := ""
+    for _, n := range nodes {
+        switch {
+            case n.is(itemSpace):
+                space += n.open.val
+            case n.is(itemString):
+                segs[len(segs)-1] += space + unquoteToken(n.open)
+                space = ""
+            default:
+                for i, elem := range strings.Split(n.open.val, ".") {
+                    if i == 0 {
+                        segs[len(segs)-1] += space + elem
+                    } else {
+                        segs = append(segs, elem)
+                    }
+                }
+                space = ""
+        }
+    }
+    return segs
+}
+
+// unquoteToken returns the text of a quoted string token, or the token as
+// it is if it is malformed.
+func unquoteToken(token item) string {
+    s, err := unquote(token.val)
+    if err != nil {
+        return token.val
+    }
+    return s
+}
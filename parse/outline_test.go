@@ -0,0 +1,61 @@
+package parse
+
+import (
+    "fmt"
+    "strings"
+    "testing"
+)
+
+const outlineInput = `include "base.conf"
+a {
+  b = ${c.d} // comment
+  "x.y" { z = 1 }
+}
+a.b = 2
+c.d = [1, { e = ${?a.b} }]
+`
+
+func TestOutline(t *testing.T) {
+    symbols, refs := Outline("test", outlineInput)
+    var got []string
+    var list func(symbols []*Symbol)
+    list = func(symbols []*Symbol) {
+        for _, s := range symbols {
+            got = append(got, fmt.Sprintf("%s %q %q %v", s.Path, s.Key, outlineInput[s.Pos:s.End], s.Object))
+            list(s.Children)
+        }
+    }
+    list(symbols)
+    expect := []string{
+        `a "a" "a {\n  b = ${c.d} // comment\n  \"x.y\" { z = 1 }\n}" true`,
+        `a.b "b" "b = ${c.d}" false`,
+        `a."x.y" "\"x.y\"" "\"x.y\" { z = 1 }" true`,
+        `a."x.y".z "z" "z = 1" false`,
+        `a.b "a.b" "a.b = 2" false`,
+        `c.d "c.d" "c.d = [1, { e = ${?a.b} }]" false`,
+        `c.d.1.e "e" "e = ${?a.b}" false`,
+    }
+    if strings.Join(got, "\n") != strings.Join(expect, "\n") {
+        t.Errorf("got\n\t%s\nexpected\n\t%s", strings.Join(got, "\n\t"), strings.Join(expect, "\n\t"))
+    }
+    got = nil
+    for _, r := range refs {
+        got = append(got, fmt.Sprintf("%q %s %q", outlineInput[r.Pos:r.End], r.Path, r.Include))
+    }
+    expect = []string{
+        `"\"base.conf\""  "base.conf"`,
+        `"${c.d}" c.d ""`,
+        `"${?a.b}" a.b ""`,
+    }
+    if strings.Join(got, "\n") != strings.Join(expect, "\n") {
+        t.Errorf("got\n\t%s\nexpected\n\t%s", strings.Join(got, "\n\t"), strings.Join(expect, "\n\t"))
+    }
+    if doc := symbols[0].Children[0].Doc; doc != "comment" {
+        t.Errorf("doc of a.b is %q", doc)
+    }
+    for _, s := range symbols {
+        if s.Key == "a" && outlineInput[s.Pos:s.KeyEnd] != "a" {
+            t.Errorf("key of a is %q", outlineInput[s.Pos:s.KeyEnd])
+        }
+    }
+}
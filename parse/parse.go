@@ -1,10 +1,10 @@
 package parse
 
 import (
+    "encoding/json"
     "fmt"
     "runtime"
     "strings"
-    "strconv"
 )
 
 // Tree is the representation of a single parsed template.
@@ -12,7 +12,13 @@ type Tree struct {
     Name      string    // name of the template represented by the tree.
     ParseName string    // name of the top-level template during parsing, for error messages.
     Root      Node     // top-level root of the tree.
+    Mode      Mode      // optional parsing features.
+    Errors    ErrorList // errors collected in RecoverErrors mode.
+    Includes  []string  // files named by include statements, found or not, in order.
     text      string    // text parsed to create the template (or its parent)
+    dir       string    // directory relative include paths start from
+    including []string  // files being included, outermost first, to detect cycles
+    noIncludes bool     // check the syntax of include statements but read no files
     // Parsing only; cleared after parse.
     lex       *lexer
     token     [3]item // three-token lookahead for parser.
@@ -20,6 +26,14 @@ type Tree struct {
     // immediate data structure
 }
 
+// A mode value is a set of flags (or 0). Modes control optional parser functionality.
+type Mode uint
+
+const (
+    RecoverErrors Mode = 1 << iota // collect errors and keep parsing instead of stopping at the first one
+    ParseComments                  // scan comments as tokens, for the formatter; the parser skips them
+)
+
 // Copy returns a copy of the Tree. Any parsing state is discarded.
 func (t *Tree) Copy() *Tree {
     if t == nil {
@@ -29,7 +43,9 @@ func (t *Tree) Copy() *Tree {
         Name:      t.Name,
         ParseName: t.ParseName,
         Root:      t.Root.Copy(),
+        Includes:  append([]string(nil), t.Includes...),
         text:      t.text,
+        dir:       t.dir,
     }
 }
 
@@ -44,6 +60,15 @@ func Parse(name, text string) (tree *Tree, err error) {
     return
 }
 
+// ParseAll parses text in RecoverErrors mode. It always returns a tree,
+// possibly incomplete, together with every error found in the input.
+func ParseAll(name, text string) (*Tree, ErrorList) {
+    t := New(name)
+    t.Mode = RecoverErrors
+    t.Parse(text)
+    return t, t.Errors
+}
+
 func (t *Tree) GetConfig() *Config {
     return &Config{root: t.Root}
 }
@@ -53,7 +78,7 @@ func (t *Tree) next() item {
     if t.peekCount > 0 {
         t.peekCount--
     } else {
-        t.token[0] = t.lex.nextItem()
+        t.token[0] = t.lexItem()
     }
     return t.token[t.peekCount]
 }
@@ -84,10 +109,20 @@ func (t *Tree) peek() item {
         return t.token[t.peekCount-1]
     }
     t.peekCount = 1
-    t.token[0] = t.lex.nextItem()
+    t.token[0] = t.lexItem()
     return t.token[0]
 }
 
+// lexItem returns the next item from the lexer, skipping comments.
+func (t *Tree) lexItem() item {
+    for {
+        token := t.lex.nextItem()
+        if token.typ != itemComment {
+            return token
+        }
+    }
+}
+
 // nextNonSpaceIgnoreNewline returns the next non-space and non-newline token.
 func (t *Tree) nextNonSpaceIgnoreNewline() (token item) {
     for {
@@ -96,6 +131,7 @@ func (t *Tree) nextNonSpaceIgnoreNewline() (token item) {
             break
         }
     }
+    t.checkLexError(token)
     return
 }
 
@@ -107,14 +143,22 @@ func (t *Tree) nextNonSpace() (token item) {
             break
         }
     }
+    t.checkLexError(token)
     return
 }
 
+// checkLexError reports an error token from the lexer.
+func (t *Tree) checkLexError(token item) {
+    if token.typ == itemError {
+        t.errorAt(token.pos, "%s", token.val)
+    }
+}
+
 // peekNonSpace returns but does not consume the next non-space token.
 func (t *Tree) peekNonSpace() (token item) {
     for {
         token = t.next()
-        if token.typ != itemSpace || token.typ != itemNewLine {
+        if token.typ != itemSpace && token.typ != itemNewLine {
             break
         }
     }
@@ -156,13 +200,76 @@ func (t *Tree) ErrorContext(n Node) (location, context string) {
     return fmt.Sprintf("%s:%d:%d", tree.ParseName, lineNum, byteNum), context
 }
 
+// location returns the 1-based line and column of pos in the input text.
+func (t *Tree) location(pos Pos) (line, col int) {
+    if int(pos) > len(t.text) {
+        pos = Pos(len(t.text))
+    }
+    text := t.text[:pos]
+    line = 1 + strings.Count(text, "\n")
+    col = int(pos) - strings.LastIndex(text, "\n")
+    return
+}
+
 // errorf formats the error and terminates processing.
 func (t *Tree) errorf(format string, args ...interface{}) {
+    t.errorAt(t.lex.lastPos, format, args...)
+}
+
+// errorAt formats the error found at pos and terminates processing. In
+// RecoverErrors mode the error is recorded and the parser unwinds to the
+// enclosing field instead.
+func (t *Tree) errorAt(pos Pos, format string, args ...interface{}) {
+    if t.Mode&RecoverErrors != 0 {
+        t.addError(pos, fmt.Sprintf(format, args...))
+        panic(errResync)
+    }
     t.Root = nil
-    format = fmt.Sprintf("template: %s:%d: %s", t.ParseName, t.lex.lineNumber(), format)
+    line, _ := t.location(pos)
+    format = fmt.Sprintf("template: %s:%d: %s", t.ParseName, line, format)
     panic(fmt.Errorf(format, args...))
 }
 
+// addError records an error found at pos.
+func (t *Tree) addError(pos Pos, msg string) {
+    line, col := t.location(pos)
+    t.Errors = append(t.Errors, &ParseError{Name: t.ParseName, Pos: pos, Line: line, Col: col, Msg: msg})
+}
+
+// synchronize skips the rest of a broken field after an error: it stops
+// after a newline or comma outside any brackets, or before a closing
+// brace of an enclosing object or EOF. It returns the type of the token
+// it stopped at. Lexer errors met on the way are recorded too.
+func (t *Tree) synchronize() itemType {
+    depth := 0
+    for {
+        token := t.next()
+        switch token.typ {
+            case itemEOF:
+                t.backup()
+                return itemEOF
+            case itemError:
+                t.addError(token.pos, token.val)
+            case itemOpenCurly, itemOpenSquare:
+                depth++
+            case itemCloseCurly:
+                if depth == 0 {
+                    t.backup()
+                    return itemCloseCurly
+                }
+                depth--
+            case itemCloseSquare:
+                if depth > 0 {
+                    depth--
+                }
+            case itemNewLine, itemComma:
+                if depth == 0 {
+                    return token.typ
+                }
+        }
+    }
+}
+
 // error terminates processing.
 func (t *Tree) error(err error) {
     t.errorf("%s", err)
@@ -188,12 +295,12 @@ func (t *Tree) expectOneOf(expected1, expected2 itemType, context string) item {
 
 // expected complains about the token and terminates processing.
 func (t *Tree) expected(token item, expectToken string) {
-    t.errorf("expected %s but token %s shows up", expectToken, token)
+    t.errorAt(token.pos, "expected %s but token %s shows up", expectToken, token)
 }
 
 // unexpected complains about the token and terminates processing.
 func (t *Tree) unexpected(token item, context string) {
-    t.errorf("unexpected %s in %s", token, context)
+    t.errorAt(token.pos, "unexpected %s in %s", token, context)
 }
 
 // recover is the handler that turns panics into returns from the top level of Parse.
@@ -203,7 +310,7 @@ func (t *Tree) recover(errp *error) {
         if _, ok := e.(runtime.Error); ok {
             panic(e)
         }
-        if t != nil {
+        if t != nil && t.lex != nil {
             t.stopParse()
         }
         *errp = e.(error)
@@ -219,6 +326,7 @@ func (t *Tree) startParse(lex *lexer) {
 
 // stopParse terminates parsing.
 func (t *Tree) stopParse() {
+    t.lex.drain()
     t.lex = nil
 }
 
@@ -226,20 +334,36 @@ func (t *Tree) stopParse() {
 // the template for execution. If either action delimiter string is empty, the
 // default ("{{" or "}}") is used. Embedded template definitions are added to
 // the treeSet map.
+// In RecoverErrors mode the tree is returned even when there are errors,
+// and the error is the ErrorList also stored in t.Errors.
 func (t *Tree) Parse(text string) (tree *Tree, err error) {
     defer t.recover(&err)
     t.ParseName = t.Name
-    t.startParse(lex(t.Name, text))
+    t.Errors = nil
+    t.Includes = nil
+    t.startParse(lex(t.Name, text, t.Mode))
     t.text = text
     t.Root = t.parse()
     t.stopParse()
-    return t, nil
+    return t, t.Errors.Err()
 }
 
 // parse is the top-level parser for a template, essentially the same
 // as itemList except it also parses {{define}} actions.
 // It runs to EOF.
 func (t *Tree) parse() (result Node) {
+    if t.Mode&RecoverErrors != 0 {
+        defer func() {
+            if e := recover(); e != nil {
+                if e != errResync {
+                    panic(e)
+                }
+                if result == nil {
+                    result = t.newMap(0)
+                }
+            }
+        }()
+    }
     switch token := t.nextNonSpaceIgnoreNewline(); token.typ {
         case itemOpenCurly, itemOpenSquare:
             result = t.parseValue(token)
@@ -255,33 +379,36 @@ func (t *Tree) parseValue(token item) Node {
     var v Node
     switch token.typ {
         case itemBool:
-        if boolValue, e := strconv.ParseBool(token.val); e != nil {
-            if (token.val == "on") {
-                v = t.newBool(token.pos, true)
-            } else if (token.val == "off") {
-                v = t.newBool(token.pos, false)
-            } else {
-                panic(e)
-            }
-        } else {
-            v = t.newBool(token.pos, boolValue)
+        switch token.val {
+            case "true", "yes", "on":
+            v = t.newBool(token.pos, true)
+            case "false", "no", "off":
+            v = t.newBool(token.pos, false)
+            default:
+            t.errorAt(token.pos, "bad boolean %q", token.val)
         }
         case itemNull:
         v = t.newNil(token.pos)
-        case itemNumber:
+        case itemNumber, itemComplex:
         var e error
-        v, e = t.newNumber(token.pos, token.val, itemNumber)
+        v, e = t.newNumber(token.pos, token.val, token.typ)
         if e != nil {
-            panic(e)
+            t.errorAt(token.pos, "%s", e)
         }
         case itemString:
-        v = t.newString(token.pos, token.val, token.val)
+        v = t.newString(token.pos, token.val, t.unquote(token))
         case itemUnquotedText:
         v = t.newString(token.pos, token.val, token.val)
         case itemOpenCurly:
-        v = t.parseObject(true)
+        m := t.parseObject(true)
+        m.Pos = token.pos // the object starts at its brace
+        v = m
         case itemOpenSquare:
-        v = t.parseArray()
+        l := t.parseArray()
+        l.Pos = token.pos
+        v = l
+        case itemSubStitution:
+        v = t.newSubstitution(token.pos, token.val)
         default:
         t.unexpected(token, "parse value")
     }
@@ -290,155 +417,323 @@ func (t *Tree) parseValue(token item) Node {
 func (t *Tree) parseObject(hadOpenCurly bool) *MapNode {
     // invoked just after the OPEN_CURLY (or START, if !hadOpenCurly)
     result := t.newMap(t.peekNonSpace().pos)
-    Loop:
-    for {
-        switch token := t.nextNonSpaceIgnoreNewline(); {
-            case token.typ == itemCloseCurly:
-            if (!hadOpenCurly) {
-                t.unexpected(token, "}")
-            }
-            break Loop
-            case token.typ == itemEOF && !hadOpenCurly:
-            t.backup()
-            break Loop
-            default:
-            // parse key
-            p := t.parseKey(token)
-            // parse '=' or '{'
-            afterKey := t.nextNonSpaceIgnoreNewline()
-            var valueToken item
-            if (afterKey.typ == itemOpenCurly) {
-                valueToken = afterKey
-            } else {
-                if (!isKeyValueSeparatorToken(afterKey)) {
-                    t.unexpected(afterKey, "= object")
+    for !t.field(result, hadOpenCurly) {
+    }
+    return result
+}
+
+// field parses one field of an object. It reports whether the end of the
+// object has been reached. In RecoverErrors mode a broken field is
+// skipped and parsing carries on with the next one.
+func (t *Tree) field(result *MapNode, hadOpenCurly bool) (done bool) {
+    if t.Mode&RecoverErrors != 0 {
+        defer func() {
+            if e := recover(); e != nil {
+                if e != errResync {
+                    panic(e)
                 }
-                t.consolidateValueTokens()
-                valueToken = t.nextNonSpaceIgnoreNewline()
+                done = t.synchronize() == itemEOF
             }
+        }()
+    }
+    return t.parseField(result, hadOpenCurly)
+}
 
-            newValue := t.parseValue(valueToken)
-
-            sepIndex := strings.Index(p, ".")
-            var key, remaining string
-            if (sepIndex == -1) {
-                key, remaining = p, ""
-            } else {
-                key, remaining = string(p[:sepIndex]), string(p[sepIndex+1:])
-            }
+func (t *Tree) parseField(result *MapNode, hadOpenCurly bool) bool {
+    switch token := t.nextNonSpaceIgnoreNewline(); {
+        case token.typ == itemCloseCurly:
+        if (!hadOpenCurly) {
+            t.unexpected(token, "}")
+        }
+        return true
+        case token.typ == itemEOF && !hadOpenCurly:
+        t.backup()
+        return true
+        case token.typ == itemEOF:
+        t.unexpected(token, "object")
+        default:
+        if (t.isInclude(token)) {
+            t.parseInclude(result)
+        } else {
+            t.parseKeyValue(result, token)
+        }
 
-            if (sepIndex == -1) {
-                if existing, ok := result.Nodes[key]; ok {
-                    newValue = newValue.withFallback(existing)
+        if (!t.checkElementSeparator()) {
+            nextToken := t.nextNonSpaceIgnoreNewline()
+            if (nextToken.typ == itemCloseCurly) {
+                if (!hadOpenCurly) {
+                    t.unexpected(nextToken, "unbalanced close brace")
                 }
-                result.Nodes[key] = newValue
+                return true
+            } else if (hadOpenCurly) {
+                t.expected(nextToken, "}")
             } else {
-                obj := t.createValueUnderPath(remaining, newValue)
-                if existing, ok := result.Nodes[key]; ok {
-                    obj = obj.withFallback(existing)
-                }
-                result.Nodes[key] = obj
-            }
-
-            if (!t.checkElementSeparator()) {
-                nextToken := t.nextNonSpaceIgnoreNewline()
-                if (nextToken.typ == itemCloseCurly) {
-                    if (!hadOpenCurly) {
-                        t.unexpected(nextToken, "unbalanced close brace")
-                    }
-                    break Loop
-                } else if (hadOpenCurly) {
-                    t.expected(nextToken, "}")
+                if (nextToken.typ == itemEOF) {
+                    t.backup()
+                    return true
                 } else {
-                    if (nextToken.typ == itemEOF) {
-                        t.backup()
-                        break
-                    } else {
-                        t.expected(nextToken, "EOF")
-                    }
+                    t.expected(nextToken, "EOF")
                 }
             }
         }
     }
-    return result
+    return false
 }
 
-func (t *Tree) parseArray() *ListNode {
-    // invoked just after the OPEN_SQUARE
-    result := t.newList(t.peekNonSpace().pos)
-    switch token := t.nextNonSpaceIgnoreNewline(); {
-        case token.typ == itemCloseSquare:
-        return result
-        case isValue(token) || token.typ == itemOpenCurly || token.typ == itemOpenSquare:
-        v := t.parseValue(token)
-        result.append(v)
-        default:
-        t.unexpected(token, "ListNode")
+// parseKeyValue parses a field starting with the key token and stores
+// its value in result.
+func (t *Tree) parseKeyValue(result *MapNode, token item) {
+    // parse key
+    p := t.parseKey(token)
+    // parse '=' or '{'
+    afterKey := t.nextNonSpaceIgnoreNewline()
+    var valueToken item
+    if (afterKey.typ == itemOpenCurly) {
+        valueToken = afterKey
+    } else {
+        if (!isKeyValueSeparatorToken(afterKey)) {
+            t.unexpected(afterKey, "= object")
+        }
+        valueToken = t.nextNonSpaceIgnoreNewline()
+    }
+
+    newValue := t.parseConcatenation(valueToken)
 
+    key, remaining := p[0], p[1:]
+    if (len(remaining) == 0) {
+        if existing, ok := result.Nodes[key]; ok {
+            newValue = newValue.withFallback(existing)
+        }
+        result.Nodes[key] = newValue
+    } else {
+        obj := t.createValueUnderPath(remaining, newValue)
+        if existing, ok := result.Nodes[key]; ok {
+            obj = obj.withFallback(existing)
+        }
+        result.Nodes[key] = obj
     }
+}
 
+func (t *Tree) parseArray() *ListNode {
+    // invoked just after the OPEN_SQUARE
+    result := t.newList(t.peekNonSpace().pos)
     for {
-        var token item
+        token := t.nextNonSpaceIgnoreNewline()
+        if (token.typ == itemCloseSquare) {
+            // empty list, or we allow one trailing comma
+            return result
+        }
+        result.append(t.parseConcatenation(token))
         if (!t.checkElementSeparator()) {
             token = t.nextNonSpaceIgnoreNewline()
-            if (token.typ == itemCloseSquare) {
-                break
+            if (token.typ != itemCloseSquare) {
+                t.unexpected(token, "ListNode")
             }
+            return result
         }
-        nextToken := t.peek()
-        if (nextToken.typ != itemOpenCurly && nextToken.typ != itemOpenSquare) {
-            t.consolidateValueTokens()
+    }
+}
+
+// unquote returns the text of a quoted string token. Double quoted strings
+// use the JSON escapes; raw strings in backquotes are taken as they are.
+func (t *Tree) unquote(token item) string {
+    s, err := unquote(token.val)
+    if err != nil {
+        t.errorAt(token.pos, "%s", err)
+    }
+    return s
+}
+
+// unquote returns the text of the quoted string quoted, as Tree.unquote
+// does, or an error if it is malformed.
+func unquote(quoted string) (string, error) {
+    if strings.HasPrefix(quoted, "`") {
+        if len(quoted) < 2 || !strings.HasSuffix(quoted, "`") {
+            return "", fmt.Errorf("bad quoted string %s", quoted)
         }
+        return quoted[1 : len(quoted)-1], nil
+    }
+    var s string
+    if err := json.Unmarshal([]byte(quoted), &s); err != nil {
+        return "", fmt.Errorf("bad quoted string %s", quoted)
+    }
+    return s, nil
+}
 
-        token = t.nextNonSpaceIgnoreNewline()
-        if (isValue(token) || token.typ == itemOpenCurly || token.typ == itemOpenSquare) {
-            v := t.parseValue(token)
-            result.append(v)
-        } else if (token.typ == itemCloseSquare) {
-            // we allow one trailing comma
+// parseKey parses a key, which is a path expression: unquoted text is split
+// into path elements at dots, quoted strings are never split, and the
+// whitespace between the parts of a key is kept.
+func (t *Tree) parseKey(token item) []string {
+    if (!isKeyToken(token)) {
+        t.unexpected(token, "object key")
+    }
+    path := []string{""}
+    quoted := []bool{false}
+    last := len(path) - 1
+    space := ""
+    for {
+        switch {
+            case token.typ == itemSpace:
+            space += token.val
+            case token.typ == itemString:
+            path[last] += space + t.unquote(token)
+            quoted[last] = true
+            space = ""
+            case isKeyToken(token):
+            for i, elem := range strings.Split(token.val, ".") {
+                if (i == 0) {
+                    path[last] += space + elem
+                } else {
+                    path = append(path, elem)
+                    quoted = append(quoted, false)
+                    last++
+                }
+            }
+            space = ""
+            default:
             t.backup()
-        } else {
-            t.unexpected(token, "ListNode")
+            for i, elem := range path {
+                if (elem == "" && !quoted[i]) {
+                    t.errorAt(token.pos, "empty path element in key %q", strings.Join(path, "."))
+                }
+            }
+            return path
         }
+        token = t.next()
     }
-    return result
 }
 
-
-func (t *Tree) parseKey(token item) string {
-    return token.val
+func isKeyToken(token item) bool {
+    switch token.typ {
+        case itemString, itemUnquotedText, itemNumber, itemBool, itemNull:
+        return true
+    }
+    return false
 }
 
-func (t *Tree) consolidateValueTokens() {
-    var tokens []item
-    token := t.nextNonSpaceIgnoreNewline()
+// parseConcatenation parses a value concatenation starting at token: values
+// separated by nothing but whitespace on one line. Strings and other simple
+// values concatenate into a string keeping the whitespace between them, as
+// do lists into a list and objects into their merge, later keys winning.
+// A concatenation with substitutions is joined when the config is resolved.
+func (t *Tree) parseConcatenation(token item) Node {
+    if (!isConcatenable(token)) {
+        t.unexpected(token, "parse value")
+    }
+    var (
+        values []Node
+        spaces []string // the whitespace before each value
+        texts  []string // the text of each value, if it is a simple value
+        end    Pos      // end of the last value
+        space  string
+    )
     for {
-        if (isValue(token) || token.typ == itemUnquotedText) {
-            tokens = append(tokens, token)
-        } else {
-            break
+        switch {
+            case token.typ == itemSpace:
+            space += token.val
+            case isConcatenable(token):
+            text := token.val
+            if (token.typ == itemString) {
+                text = t.unquote(token)
+            }
+            values = append(values, t.parseValue(token))
+            spaces = append(spaces, space)
+            texts = append(texts, text)
+            end = token.pos + Pos(len(token.val))
+            space = ""
+            default:
+            t.backup()
+            return t.concatenate(values, spaces, texts, end)
         }
-        token = t.nextNonSpace()
+        token = t.next()
     }
+}
 
-    if (tokens == nil) {
-        t.backup()
-        return
-    } else {
-        t.backup2(consolidate(tokens))
+func isConcatenable(token item) bool {
+    switch token.typ {
+        case itemUnquotedText, itemComplex, itemSubStitution, itemOpenCurly, itemOpenSquare:
+        return true
     }
+    return isValue(token)
 }
 
-func consolidate(tokens []item) item {
-    if (len(tokens) == 1) {
-        return tokens[0]
-    } else {
-        consolidatedToken := tokens[0].val
-        for i := 1; i < len(tokens); i++ {
-            consolidatedToken += " " + tokens[i].val
+// concatenate joins the values of a concatenation; end is the end of the
+// last one in the input text.
+func (t *Tree) concatenate(values []Node, spaces, texts []string, end Pos) Node {
+    if (len(values) == 1) {
+        return values[0]
+    }
+    start := values[0].Position()
+    for _, v := range values {
+        if (v.Type() == NodeSubstitution) {
+            // Simple values can only end up in a string: keep their text.
+            for i, v := range values {
+                switch v.Type() {
+                    case NodeMap, NodeList, NodeSubstitution:
+                    default:
+                    values[i] = t.newString(v.Position(), v.String(), texts[i])
+                }
+            }
+            return t.newConcat(start, values, spaces)
+        }
+    }
+    n, bad := join(t, start, values, spaces, texts, t.text[start:end])
+    if (bad != nil) {
+        t.errorAt(bad.Position(), "cannot concatenate %s and %s", typeName(values[0]), typeName(bad))
+    }
+    return n
+}
+
+// join concatenates values, which must not be substitutions: objects merge
+// into the last one, lists append, and simple values join into a string
+// from their texts and the spaces between them. If a value cannot be
+// concatenated with the first one, it is returned as bad.
+func join(t *Tree, pos Pos, values []Node, spaces, texts []string, quoted string) (n Node, bad Node) {
+    first := values[0]
+    switch first.(type) {
+        case *MapNode:
+        for _, v := range values {
+            if (v.Type() != NodeMap) {
+                return nil, v
+            }
+        }
+        var merged Node = values[len(values)-1]
+        for i := len(values) - 2; i >= 0; i-- {
+            merged = merged.withFallback(values[i])
         }
-        return item{itemString, tokens[0].pos, consolidatedToken}
+        return merged, nil
+        case *ListNode:
+        result := t.newList(pos)
+        for _, v := range values {
+            list, ok := v.(*ListNode)
+            if (!ok) {
+                return nil, v
+            }
+            result.Nodes = append(result.Nodes, list.Nodes...)
+        }
+        return result, nil
+    }
+    text := texts[0]
+    for i, v := range values {
+        if (v.Type() == NodeMap || v.Type() == NodeList) {
+            return nil, v
+        }
+        if (i > 0) {
+            text += spaces[i] + texts[i]
+        }
+    }
+    return t.newString(pos, quoted, text), nil
+}
+
+// typeName names the type of n, with its article, in error messages.
+func typeName(n Node) string {
+    switch n.Type() {
+        case NodeMap:
+        return "an object"
+        case NodeList:
+        return "a list"
     }
+    return "a string"
 }
 
 func isKeyValueSeparatorToken(token item) bool {
@@ -469,8 +764,7 @@ func (t *Tree) checkElementSeparator() bool {
     }
 }
 
-func (t *Tree) createValueUnderPath(remaining string, newValue Node) Node {
-    ps := strings.Split(remaining, ".")
+func (t *Tree) createValueUnderPath(ps []string, newValue Node) Node {
     prevObj := newValue
     for i := len(ps) - 1; i >= 0; i-- {
         obj := t.newMap(newValue.Position())
@@ -69,7 +69,42 @@ var parseTests = []parseTest{
             arr = [true, false]
         }`,
         noError,
-        `akka = (count = (10)arr = (truefalse))`},
+        `akka = (arr = (truefalse)count = (10))`},
+    {"concatenation keeps whitespace", "a = foo  bar\t baz  ", noError,
+        "a = (foo  bar\t baz)"},
+    {"concatenation with quoted string", `a = "foo" bar`, noError,
+        `a = ("foo" bar)`},
+    {"unquoted path", `path = /usr/local/bin  # comment`, noError,
+        `path = (/usr/local/bin)`},
+    {"address", `host = 127.0.0.1`, noError,
+        `host = (127.0.0.1)`},
+    {"unit", `timeout = 10s`, noError,
+        `timeout = (10s)`},
+    {"list concatenation", `a = [1] [2, 3]`, noError,
+        `a = (123)`},
+    {"object concatenation", `a = {x : 1, y : 2} {y : 3}`, noError,
+        `a = (x = (1)y = (3))`},
+    {"object concatenation after key", `a {x : 1} {y : 2}`, noError,
+        `a = (x = (1)y = (2))`},
+    {"list of concatenations", `a = [foo bar, 1 2]`, noError,
+        `a = (foo bar1 2)`},
+    {"quoted key", `"a.b".c = 1`, noError,
+        `a.b = (c = (1))`},
+    {"key with spaces", `a b.c d = 1`, noError,
+        `a b = (c d = (1))`},
+    {"substitution concatenation", `a = ${b} { c = 1 } "x"  ${?d}`, noError,
+        `a = (${b} c = (1) "x"  ${?d})`},
+    {"keywords", `a = [true, yes, on, false, no, off, null, nil, nullable]`, noError,
+        `a = (truetruetruefalsefalsefalsenullnilnullable)`},
+    {"keyword in concatenation", `a = true love`, noError,
+        `a = (true love)`},
+    {"json", `{"a": null, "b": [1, true], "c": {"d": "e"}}`, noError,
+        `a = (null)b = (1true)c = (d = ("e"))`},
+    {"list and object", `a = [1] {b : 1}`, hasError, ``},
+    {"string and list", `a = foo [1]`, hasError, ``},
+    {"object and string", `a = {b : 1} foo`, hasError, ``},
+    {"list and string", `a = [1] foo`, hasError, ``},
+    {"empty key element", `a..b = 1`, hasError, ``},
 }
 
 func testParse(doCopy bool, t *testing.T) {
@@ -106,3 +141,75 @@ func testParse(doCopy bool, t *testing.T) {
 func TestParse(t *testing.T) {
     testParse(false, t)
 }
+
+type recoverTest struct {
+    name   string
+    input  string
+    errors []string // expected errors, as line:col: message
+    result string   // the best-effort tree
+}
+
+var recoverTests = []recoverTest{
+    {"no errors", "a = 1\nb = 2", nil,
+        `a = (1)b = (2)`},
+    {"bad concatenations", "a = {b : 1} foo\nc = [1] {b : 1}\nd = 1", []string{
+        `1:13: cannot concatenate an object and a string`, `2:9: cannot concatenate a list and an object`},
+        `d = (1)`},
+    {"two bad fields",
+        "a = 1\nb = = 2\nc = 3\nd } 4\ne = 5",
+        []string{`2:5: unexpected "=" in parse value`, `4:3: unexpected "}" in = object`},
+        `a = (1)c = (3)e = (5)`},
+    {"comma separated",
+        "a = 1, b : : 2, c = 3",
+        []string{`1:12: unexpected ":" in parse value`},
+        `a = (1)c = (3)`},
+    {"nested object",
+        "a {\n  x = 1\n  y = ]\n  z = 3\n}\nb = 2",
+        []string{`3:7: unexpected "]" in parse value`},
+        `a = (x = (1)z = (3))b = (2)`},
+    {"unclosed object",
+        "a {\n  x = 1\n",
+        []string{`3:1: unexpected EOF in object`},
+        `a = (x = (1))`},
+    {"lex errors",
+        "a = 1\nb = \"abc\nc = 3\nd = ^\ne = 5",
+        []string{`2:5: unterminated quoted string`, `4:5: unrecognized character in action: U+005E '^'`},
+        `a = (1)c = (3)e = (5)`},
+    {"stray close brace",
+        "a = 1\n}\nb = 2",
+        []string{`2:1: unexpected "}" in }`},
+        `a = (1)b = (2)`},
+}
+
+func TestParseAll(t *testing.T) {
+    for _, test := range recoverTests {
+        tree, errs := ParseAll(test.name, test.input)
+        var got []string
+        for _, e := range errs {
+            got = append(got, fmt.Sprintf("%d:%d: %s", e.Line, e.Col, e.Msg))
+        }
+        if fmt.Sprint(got) != fmt.Sprint(test.errors) {
+            t.Errorf("%s: got errors\n\t%q\nexpected\n\t%q", test.name, got, test.errors)
+        }
+        if result := tree.Root.String(); result != test.result {
+            t.Errorf("%s: got\n\t%v\nexpected\n\t%v", test.name, result, test.result)
+        }
+    }
+}
+
+func TestParseAllError(t *testing.T) {
+    _, err := New("app.conf").Parse("a = = 1")
+    if _, ok := err.(ErrorList); ok || err == nil {
+        t.Fatalf("expected a plain error without RecoverErrors, got %#v", err)
+    }
+    tree := New("app.conf")
+    tree.Mode = RecoverErrors
+    _, err = tree.Parse("a = = 1\nb = = 2\n")
+    list, ok := err.(ErrorList)
+    if !ok || len(list) != 2 {
+        t.Fatalf("expected an ErrorList with 2 errors, got %#v", err)
+    }
+    if s := list.Error(); s != `app.conf:1:5: unexpected "=" in parse value (and 1 more errors)` {
+        t.Errorf("unexpected message %q", s)
+    }
+}
@@ -0,0 +1,119 @@
+package parse
+
+import (
+    "errors"
+    "fmt"
+    "strconv"
+)
+
+// Errors reported in a PathError.
+var (
+    ErrMissing         = errors.New("no such key")
+    ErrNotObject       = errors.New("value is not an object or list")
+    ErrBadIndex        = errors.New("list index is not a number")
+    ErrIndexOutOfRange = errors.New("list index out of range")
+    ErrBadPath         = errors.New("malformed path")
+)
+
+// PathError records a failed path lookup and the segment that caused it.
+type PathError struct {
+    Path    string // the path that was looked up
+    Segment string // the segment that could not be followed
+    Index   int    // index of Segment among the segments of Path
+    Err     error  // one of the ErrXxx values above
+}
+
+func (e *PathError) Error() string {
+    return fmt.Sprintf("path %q: segment %q: %v", e.Path, e.Segment, e.Err)
+}
+
+// parsePath splits a path into its segments. Segments are separated by
+// dots; a segment may be a quoted string to include dots, and may be
+// followed by list indexes in brackets, so that "a.b[0]" and "a.b.0" are
+// the same path. The empty path has no segments.
+func parsePath(path string) ([]string, error) {
+    var segs []string
+    if path == "" {
+        return segs, nil
+    }
+    bad := func(i int) error {
+        return &PathError{Path: path, Segment: path[i:], Index: len(segs), Err: ErrBadPath}
+    }
+    i := 0
+    for {
+        start := i
+        if i < len(path) && path[i] == '"' {
+            j := i + 1
+            for ; j < len(path) && path[j] != '"'; j++ {
+                if path[j] == '\\' {
+                    j++
+                }
+            }
+            if j >= len(path) {
+                return nil, bad(start)
+            }
+            key, err := strconv.Unquote(path[i : j+1])
+            if err != nil {
+                return nil, bad(start)
+            }
+            segs = append(segs, key)
+            i = j + 1
+        } else {
+            for i < len(path) && path[i] != '.' && path[i] != '[' {
+                i++
+            }
+            if i > start {
+                segs = append(segs, path[start:i])
+            } else if i == len(path) || path[i] != '[' {
+                return nil, bad(start)
+            }
+        }
+        for i < len(path) && path[i] == '[' {
+            j := i + 1
+            for j < len(path) && '0' <= path[j] && path[j] <= '9' {
+                j++
+            }
+            if j == i+1 || j >= len(path) || path[j] != ']' {
+                return nil, bad(i)
+            }
+            segs = append(segs, path[i+1:j])
+            i = j + 1
+        }
+        if i == len(path) {
+            return segs, nil
+        }
+        if path[i] != '.' {
+            return nil, bad(i)
+        }
+        i++
+        if i == len(path) {
+            return nil, bad(i - 1)
+        }
+    }
+}
+
+// lookup follows the segments of path from n.
+func lookup(n Node, path string, segs []string) (Node, error) {
+    for i, seg := range segs {
+        switch v := n.(type) {
+            case *MapNode:
+                next, ok := v.Nodes[seg]
+                if !ok {
+                    return nil, &PathError{Path: path, Segment: seg, Index: i, Err: ErrMissing}
+                }
+                n = next
+            case *ListNode:
+                idx, err := strconv.Atoi(seg)
+                if err != nil {
+                    return nil, &PathError{Path: path, Segment: seg, Index: i, Err: ErrBadIndex}
+                }
+                if idx < 0 || idx >= len(v.Nodes) {
+                    return nil, &PathError{Path: path, Segment: seg, Index: i, Err: ErrIndexOutOfRange}
+                }
+                n = v.Nodes[idx]
+            default:
+                return nil, &PathError{Path: path, Segment: seg, Index: i, Err: ErrNotObject}
+        }
+    }
+    return n, nil
+}
@@ -0,0 +1,325 @@
+package parse
+
+import (
+    "bytes"
+    "encoding/json"
+    "fmt"
+    "strconv"
+    "strings"
+)
+
+// Format is a syntax a config can be rendered in.
+type Format int
+
+const (
+    FormatHOCON Format = iota // HOCON, one field per line
+    FormatJSON                // JSON, indented
+    FormatYAML                // YAML, block style
+)
+
+func (f Format) String() string {
+    switch f {
+        case FormatHOCON:
+            return "hocon"
+        case FormatJSON:
+            return "json"
+        case FormatYAML:
+            return "yaml"
+    }
+    return fmt.Sprintf("Format(%d)", int(f))
+}
+
+// ParseFormat returns the format called name: hocon or conf, json, and
+// yaml or yml.
+func ParseFormat(name string) (Format, error) {
+    switch strings.ToLower(name) {
+        case "hocon", "conf":
+            return FormatHOCON, nil
+        case "json":
+            return FormatJSON, nil
+        case "yaml", "yml":
+            return FormatYAML, nil
+    }
+    return 0, fmt.Errorf("unknown format %q", name)
+}
+
+// Render returns c in the given format, with object keys sorted and an
+// indent of two spaces. Comments and the original layout are not kept.
+// JSON and YAML have no substitutions: a config rendered in them must be
+// resolved first. Nor do they have complex numbers.
+func (c *Config) Render(format Format) ([]byte, error) {
+    var b bytes.Buffer
+    if c.root == nil {
+        return b.Bytes(), nil
+    }
+    if format != FormatHOCON {
+        if err := checkPlain(c.root, format); err != nil {
+            return nil, err
+        }
+    }
+    switch format {
+        case FormatHOCON:
+            if m, ok := c.root.(*MapNode); ok {
+                writeHOCONFields(&b, m, "")
+            } else {
+                writeHOCON(&b, c.root, "")
+                b.WriteByte('\n')
+            }
+        case FormatJSON:
+            writeJSON(&b, c.root, "")
+            b.WriteByte('\n')
+        case FormatYAML:
+            writeYAML(&b, c.root, "")
+        default:
+            return nil, fmt.Errorf("unknown format %v", format)
+    }
+    return b.Bytes(), nil
+}
+
+// checkPlain returns an error for the first value below n that format
+// cannot represent.
+func checkPlain(n Node, format Format) error {
+    switch v := n.(type) {
+        case *MapNode:
+            for _, k := range v.sortedKeys() {
+                if err := checkPlain(v.Nodes[k], format); err != nil {
+                    return err
+                }
+            }
+        case *ListNode:
+            for _, elem := range v.Nodes {
+                if err := checkPlain(elem, format); err != nil {
+                    return err
+                }
+            }
+        case *SubstitutionNode, *ConcatNode:
+            return fmt.Errorf("%s: cannot render unresolved %s as %s", location(n), n, format)
+        case *NumberNode:
+            if _, ok := plainNumber(v); !ok {
+                return fmt.Errorf("%s: cannot render %s as %s", location(n), n, format)
+            }
+    }
+    return nil
+}
+
+// plainNumber returns n as a JSON number.
+func plainNumber(n *NumberNode) (string, bool) {
+    switch {
+        case n.IsComplex && imag(n.Complex128) != 0:
+            return "", false
+        case n.Text != "" && n.Text[0] != '+' && json.Valid([]byte(n.Text)):
+            return n.Text, true
+        case n.BigInt != nil:
+            return n.BigInt.String(), true
+        case n.IsFloat:
+            return strconv.FormatFloat(n.Float64, 'g', -1, 64), true
+    }
+    return "", false
+}
+
+// quote returns s as a JSON string, which HOCON and YAML read too.
+func quote(s string) string {
+    var b bytes.Buffer
+    enc := json.NewEncoder(&b)
+    enc.SetEscapeHTML(false)
+    enc.Encode(s)
+    return strings.TrimSuffix(b.String(), "\n")
+}
+
+func hoconKey(k string) string {
+    if needsQuote(k) {
+        return quote(k)
+    }
+    return k
+}
+
+// writeHOCONFields writes the fields of m, one per line.
+func writeHOCONFields(b *bytes.Buffer, m *MapNode, indent string) {
+    for _, k := range m.sortedKeys() {
+        v := m.Nodes[k]
+        b.WriteString(indent)
+        b.WriteString(hoconKey(k))
+        if v.Type() == NodeMap {
+            b.WriteByte(' ')
+        } else {
+            b.WriteString(" = ")
+        }
+        writeHOCON(b, v, indent)
+        b.WriteByte('\n')
+    }
+}
+
+// writeHOCON writes the value n, whose first line is already indented.
+func writeHOCON(b *bytes.Buffer, n Node, indent string) {
+    if isSecret(n) {
+        b.WriteString(quote(Redacted))
+        return
+    }
+    switch v := n.(type) {
+        case *MapNode:
+            if len(v.Nodes) == 0 {
+                b.WriteString("{}")
+                return
+            }
+            b.WriteString("{\n")
+            writeHOCONFields(b, v, indent+"  ")
+            b.WriteString(indent + "}")
+        case *ListNode:
+            if len(v.Nodes) == 0 {
+                b.WriteString("[]")
+                return
+            }
+            b.WriteString("[\n")
+            for _, elem := range v.Nodes {
+                b.WriteString(indent + "  ")
+                writeHOCON(b, elem, indent+"  ")
+                b.WriteString(",\n")
+            }
+            b.WriteString(indent + "]")
+        case *StringNode:
+            b.WriteString(quote(v.Text))
+        case *TextNode:
+            b.WriteString(quote(string(v.Text)))
+        case *SubstitutionNode:
+            b.WriteString("${")
+            if v.Optional {
+                b.WriteByte('?')
+            }
+            b.WriteString(v.Path)
+            b.WriteByte('}')
+        case *ConcatNode:
+            for i, child := range v.Nodes {
+                if i > 0 {
+                    b.WriteString(v.Spaces[i])
+                }
+                writeHOCON(b, child, indent)
+            }
+        default:
+            b.WriteString(n.String())
+    }
+}
+
+// writeJSON writes the value n, whose first line is already indented.
+func writeJSON(b *bytes.Buffer, n Node, indent string) {
+    switch v := n.(type) {
+        case *MapNode:
+            if len(v.Nodes) == 0 {
+                b.WriteString("{}")
+                return
+            }
+            b.WriteString("{\n")
+            for i, k := range v.sortedKeys() {
+                if i > 0 {
+                    b.WriteString(",\n")
+                }
+                b.WriteString(indent + "  " + quote(k) + ": ")
+                writeJSON(b, v.Nodes[k], indent+"  ")
+            }
+            b.WriteString("\n" + indent + "}")
+        case *ListNode:
+            if len(v.Nodes) == 0 {
+                b.WriteString("[]")
+                return
+            }
+            b.WriteString("[\n")
+            for i, elem := range v.Nodes {
+                if i > 0 {
+                    b.WriteString(",\n")
+                }
+                b.WriteString(indent + "  ")
+                writeJSON(b, elem, indent+"  ")
+            }
+            b.WriteString("\n" + indent + "]")
+        default:
+            b.WriteString(plainScalar(n))
+    }
+}
+
+// plainScalar returns a scalar as JSON, which is also YAML.
+func plainScalar(n Node) string {
+    if isSecret(n) {
+        return quote(Redacted)
+    }
+    switch v := n.(type) {
+        case *StringNode:
+            return quote(v.Text)
+        case *TextNode:
+            return quote(string(v.Text))
+        case *NumberNode:
+            s, _ := plainNumber(v)
+            return s
+    }
+    return n.String()
+}
+
+// yamlKey returns k as a YAML key, quoted unless it is a plain word that
+// YAML would not read as anything but a string.
+func yamlKey(k string) string {
+    switch strings.ToLower(k) {
+        case "", "true", "false", "yes", "no", "on", "off", "y", "n", "null", "~":
+            return quote(k)
+    }
+    for i, r := range k {
+        letter := 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || r == '_'
+        if !letter && (i == 0 || !('0' <= r && r <= '9' || r == '-' || r == '.')) {
+            return quote(k)
+        }
+    }
+    return k
+}
+
+// writeYAML writes the value n as a block at the given indent, ending with
+// a newline. Objects and lists that are not empty start on a new line.
+func writeYAML(b *bytes.Buffer, n Node, indent string) {
+    switch v := n.(type) {
+        case *MapNode:
+            if len(v.Nodes) == 0 {
+                b.WriteString("{}\n")
+                return
+            }
+            for _, k := range v.sortedKeys() {
+                b.WriteString(indent + yamlKey(k) + ":")
+                writeYAMLChild(b, v.Nodes[k], indent+"  ", false)
+            }
+        case *ListNode:
+            if len(v.Nodes) == 0 {
+                b.WriteString("[]\n")
+                return
+            }
+            for _, elem := range v.Nodes {
+                b.WriteString(indent + "-")
+                writeYAMLChild(b, elem, indent+"  ", true)
+            }
+        default:
+            b.WriteString(plainScalar(n) + "\n")
+    }
+}
+
+// writeYAMLChild writes the value of a key or list element. In a list a
+// non-empty object or list starts on the line of its dash.
+func writeYAMLChild(b *bytes.Buffer, n Node, indent string, inList bool) {
+    if isEmptyCollection(n) || (n.Type() != NodeMap && n.Type() != NodeList) {
+        b.WriteByte(' ')
+        writeYAML(b, n, indent)
+        return
+    }
+    if !inList {
+        b.WriteByte('\n')
+        writeYAML(b, n, indent)
+        return
+    }
+    var child bytes.Buffer
+    writeYAML(&child, n, indent)
+    b.WriteByte(' ')
+    b.WriteString(strings.TrimPrefix(child.String(), indent))
+}
+
+func isEmptyCollection(n Node) bool {
+    switch v := n.(type) {
+        case *MapNode:
+            return len(v.Nodes) == 0
+        case *ListNode:
+            return len(v.Nodes) == 0
+    }
+    return false
+}
@@ -0,0 +1,129 @@
+package parse
+
+import (
+    "strings"
+    "testing"
+)
+
+const renderInput = `
+b { c = [1, {d = x}], "e.f" = "quoted\ttext", g = {}, h = [] }
+a = 1.50
+n = null
+t = on
+`
+
+var renderTests = []struct {
+    format Format
+    result string
+}{
+    {FormatHOCON, `a = 1.50
+b {
+  c = [
+    1,
+    {
+      d = "x"
+    },
+  ]
+  "e.f" = "quoted\ttext"
+  g {}
+  h = []
+}
+n = null
+t = true
+`},
+    {FormatJSON, `{
+  "a": 1.50,
+  "b": {
+    "c": [
+      1,
+      {
+        "d": "x"
+      }
+    ],
+    "e.f": "quoted\ttext",
+    "g": {},
+    "h": []
+  },
+  "n": null,
+  "t": true
+}
+`},
+    {FormatYAML, `a: 1.50
+b:
+  c:
+    - 1
+    - d: "x"
+  e.f: "quoted\ttext"
+  g: {}
+  h: []
+"n": null
+t: true
+`},
+}
+
+func TestRender(t *testing.T) {
+    conf := testConfig(t, renderInput)
+    for _, test := range renderTests {
+        out, err := conf.Render(test.format)
+        if err != nil {
+            t.Errorf("%v: %v", test.format, err)
+            continue
+        }
+        if string(out) != test.result {
+            t.Errorf("%v: got\n%s\nexpected\n%s", test.format, out, test.result)
+        }
+    }
+    // The HOCON output reads back as the same config.
+    out, _ := conf.Render(FormatHOCON)
+    if again := testConfig(t, string(out)); !again.Equal(conf) {
+        t.Errorf("HOCON output differs from the input: %s", again)
+    }
+}
+
+func TestRenderNotPlain(t *testing.T) {
+    for _, input := range []string{`a = ${b}, b = 1`, `a = 1+2i`} {
+        conf := testConfig(t, input)
+        for _, format := range []Format{FormatJSON, FormatYAML} {
+            if _, err := conf.Render(format); err == nil {
+                t.Errorf("%q as %v: expected an error", input, format)
+            }
+        }
+        if _, err := conf.Render(FormatHOCON); err != nil {
+            t.Errorf("%q as HOCON: %v", input, err)
+        }
+    }
+    conf := testConfig(t, `a = 0x1F, b = [[1, 2], []], "true" = 1`)
+    out, err := conf.Render(FormatYAML)
+    expect := "a: 31\nb:\n  - - 1\n    - 2\n  - []\n\"true\": 1\n"
+    if err != nil || string(out) != expect {
+        t.Errorf("got\n%s\nexpected\n%s", out, expect)
+    }
+}
+
+func TestCheckValid(t *testing.T) {
+    reference := testConfig(t, `a { port = 80, debug = off, name = x, hosts = [], any = null }, b = 1`)
+    conf := testConfig(t, `a { port = "8080", debug = yes, name = 3, hosts = [h], any = {} }, b = 2`)
+    if err := conf.CheckValid(reference); err != nil {
+        t.Errorf("unexpected error: %v", err)
+    }
+    conf = testConfig(t, `a { port = eighty, debug = 1, name = [], hosts = h }, c = 1`)
+    err := conf.CheckValid(reference)
+    errs, ok := err.(ValidationErrors)
+    if !ok {
+        t.Fatalf("expected ValidationErrors, got %v", err)
+    }
+    var got []string
+    for _, e := range errs {
+        got = append(got, e.Error())
+    }
+    expect := []string{
+        "test:1:28: a.debug: expected boolean, got number",
+        "test:1:50: a.hosts: expected list, got string",
+        "test:1:38: a.name: expected string, got list",
+        "test:1:12: a.port: expected number, got string",
+        "test:1:1: b: missing, expected number",
+    }
+    if strings.Join(got, "\n") != strings.Join(expect, "\n") {
+        t.Errorf("got\n\t%s\nexpected\n\t%s", strings.Join(got, "\n\t"), strings.Join(expect, "\n\t"))
+    }
+}
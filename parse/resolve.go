@@ -0,0 +1,300 @@
+package parse
+
+import (
+    "fmt"
+    "os"
+    "strconv"
+)
+
+// Resolve returns a copy of c with every substitution replaced by the value
+// it refers to. Substitution paths are looked up from the root of c; a path
+// missing from the config falls back to the environment variable of that
+// name. A missing optional substitution, ${?path}, removes the field it is
+// the value of, or is left out of the concatenation it is part of.
+// Self-referential substitutions, which would refer to an earlier value
+// of the same field, are reported as cycles.
+//
+// A substitution whose path starts with the scheme of a registered
+// Resolver, such as ${env:HOME} or ${file:/run/secrets/db}, gets its
+// value, a string, from that resolver instead. Encrypted values, as made
+// by Keyring.Encrypt, are decrypted with the keyring of the environment.
+func (c *Config) Resolve() (*Config, error) {
+    return c.ResolveWith(nil)
+}
+
+// ResolveWith is Resolve with options; nil options are the defaults.
+func (c *Config) ResolveWith(opts *ResolveOptions) (*Config, error) {
+    if opts == nil {
+        opts = new(ResolveOptions)
+    }
+    if c.root == nil {
+        return c, nil
+    }
+    r := &resolver{
+        root:      c.root.Copy(),
+        active:    make(map[Node]bool),
+        resolved:  make(map[Node]bool),
+        opts:      opts,
+        resolvers: make(map[string]Resolver),
+        externals: make(map[string]external),
+        keyring:   opts.Keyring,
+        encrypted: make(map[*StringNode]Path),
+    }
+    if !opts.Offline {
+        // Before resolving moves list elements.
+        encryptedPaths(r.root, nil, r.encrypted)
+    }
+    resolversMu.RLock()
+    for scheme, res := range resolvers {
+        r.resolvers[scheme] = res
+    }
+    resolversMu.RUnlock()
+    for scheme, res := range opts.Resolvers {
+        r.resolvers[scheme] = res
+    }
+    root, err := r.resolve(r.root)
+    if err != nil {
+        return nil, err
+    }
+    return c.wrap(root), nil
+}
+
+// IsResolved reports whether c contains no substitutions.
+func (c *Config) IsResolved() bool {
+    return isResolved(c.root)
+}
+
+func isResolved(n Node) bool {
+    switch v := n.(type) {
+        case *SubstitutionNode, *ConcatNode:
+            return false
+        case *MapNode:
+            for _, child := range v.Nodes {
+                if !isResolved(child) {
+                    return false
+                }
+            }
+        case *ListNode:
+            for _, child := range v.Nodes {
+                if !isResolved(child) {
+                    return false
+                }
+            }
+    }
+    return true
+}
+
+// resolver holds the state of a resolution. It replaces substitutions in
+// place in its private copy of the tree.
+type resolver struct {
+    root      Node
+    active    map[Node]bool // nodes being resolved, to detect cycles
+    resolved  map[Node]bool // nodes known not to contain substitutions
+    opts      *ResolveOptions
+    resolvers map[string]Resolver // by scheme
+    externals map[string]external // the values of resolvers, by scheme:ref
+    keyring   *Keyring            // decrypts values, loaded when first needed
+    encrypted map[*StringNode]Path // the paths of the encrypted values
+}
+
+// location returns the position of n for error messages.
+func location(n Node) string {
+    o := originOf(n)
+    if o.Line == 0 {
+        return fmt.Sprintf("offset %d", n.Position())
+    }
+    return o.String()
+}
+
+// resolve returns n with its substitutions resolved. It returns nil for a
+// missing optional substitution.
+func (r *resolver) resolve(n Node) (Node, error) {
+    if n == nil || r.resolved[n] {
+        return n, nil
+    }
+    if r.active[n] {
+        return nil, fmt.Errorf("%s: cycle in substitution %s", location(n), n)
+    }
+    r.active[n] = true
+    defer delete(r.active, n)
+    switch v := n.(type) {
+        case *MapNode:
+            for _, k := range v.sortedKeys() {
+                child, err := r.resolve(v.Nodes[k])
+                if err != nil {
+                    return nil, err
+                }
+                if child == nil {
+                    delete(v.Nodes, k)
+                } else {
+                    v.Nodes[k] = child
+                }
+            }
+        case *ListNode:
+            var nodes []Node
+            for _, elem := range v.Nodes {
+                child, err := r.resolve(elem)
+                if err != nil {
+                    return nil, err
+                }
+                if child != nil {
+                    nodes = append(nodes, child)
+                }
+            }
+            v.Nodes = nodes
+        case *SubstitutionNode:
+            target, err := r.lookup(v)
+            if err != nil {
+                return nil, err
+            }
+            n = nil
+            if target != nil {
+                n = target.Copy()
+                if v.Secret {
+                    markSecret(n)
+                }
+            }
+            if n, err = r.overFallback(n, v.Fallback); err != nil || n == nil {
+                return n, err
+            }
+        case *ConcatNode:
+            joined, err := r.join(v)
+            if err != nil {
+                return nil, err
+            }
+            return r.overFallback(joined, v.Fallback)
+        case *StringNode:
+            if IsEncrypted(v.Text) && !r.opts.Offline {
+                var err error
+                if n, err = r.decrypt(v); err != nil {
+                    return nil, err
+                }
+            }
+    }
+    r.resolved[n] = true
+    return n, nil
+}
+
+// lookup returns the resolved value a substitution refers to.
+func (r *resolver) lookup(s *SubstitutionNode) (Node, error) {
+    if scheme, ref, ok := splitScheme(s.Path); ok {
+        if res := r.resolvers[scheme]; res != nil {
+            return r.lookupExternal(s, scheme, ref, res)
+        }
+    }
+    segs, err := parsePath(s.Path)
+    if err != nil || len(segs) == 0 {
+        return nil, fmt.Errorf("%s: bad substitution %s", location(s), s)
+    }
+    n := r.root
+    for i, seg := range segs {
+        m, ok := n.(*MapNode)
+        if !ok {
+            n = nil
+            break
+        }
+        n = m.Nodes[seg]
+        // Objects on the way may be ancestors of s, which are being
+        // resolved; only resolve what is needed to go on.
+        if n != nil && (n.Type() != NodeMap || i == len(segs)-1) {
+            if n, err = r.resolve(n); err != nil {
+                return nil, err
+            }
+            if n == nil {
+                delete(m.Nodes, seg)
+            } else {
+                m.Nodes[seg] = n
+            }
+        }
+        if n == nil {
+            break
+        }
+    }
+    if n != nil {
+        return n, nil
+    }
+    if env, ok := os.LookupEnv(s.Path); ok {
+        return s.tr.newString(s.Pos, strconv.Quote(env), env), nil
+    }
+    if s.Optional {
+        return nil, nil
+    }
+    return nil, fmt.Errorf("%s: could not resolve substitution %s", location(s), s)
+}
+
+// overFallback returns n, the resolved value of a substitution or a
+// concatenation, merged over its fallback if both are objects, or the
+// fallback if n is missing.
+func (r *resolver) overFallback(n, fallback Node) (Node, error) {
+    if fallback == nil || n != nil && n.Type() != NodeMap {
+        return n, nil
+    }
+    fb, err := r.resolve(fallback)
+    if err != nil || n == nil {
+        return fb, err
+    }
+    if fb == nil || fb.Type() != NodeMap {
+        return n, nil
+    }
+    return n.withFallback(fb), nil
+}
+
+// join resolves the values of a concatenation and joins them.
+func (r *resolver) join(c *ConcatNode) (Node, error) {
+    var values []Node
+    var spaces, texts []string
+    for i, n := range c.Nodes {
+        v, err := r.resolve(n)
+        if err != nil {
+            return nil, err
+        }
+        if v == nil {
+            continue
+        }
+        // Substituted values are shared with their origin: copy them
+        // before merging.
+        v = v.Copy()
+        values = append(values, v)
+        spaces = append(spaces, c.Spaces[i])
+        texts = append(texts, valueText(v))
+    }
+    switch len(values) {
+        case 0:
+            return nil, nil
+        case 1:
+            if c.Secret {
+                markSecret(values[0])
+            }
+            return values[0], nil
+    }
+    joined, bad := join(c.tr, c.Pos, values, spaces, texts, "")
+    if bad != nil {
+        return nil, fmt.Errorf("%s: cannot concatenate %s and %s in %s", location(c), typeName(values[0]), typeName(bad), c)
+    }
+    if s, ok := joined.(*StringNode); ok {
+        s.Quoted = strconv.Quote(s.Text)
+        // Text joined with a secret is secret.
+        for _, v := range values {
+            s.Secret = s.Secret || isSecret(v)
+        }
+    }
+    if c.Secret {
+        markSecret(joined)
+    }
+    return joined, nil
+}
+
+// valueText returns the text of a simple value in a string concatenation.
+func valueText(n Node) string {
+    switch v := n.(type) {
+        case *StringNode:
+            return v.Text
+        case *NumberNode:
+            return v.Text
+        case *BoolNode:
+            return fmt.Sprint(v.True)
+        case *NilNode:
+            return "null"
+    }
+    return n.String()
+}
@@ -0,0 +1,115 @@
+package parse
+
+import (
+    "os"
+    "strings"
+    "testing"
+)
+
+type resolveTest struct {
+    name   string
+    input  string
+    ok     bool
+    result string
+}
+
+var resolveTests = []resolveTest{
+    {"no substitutions", `a = 1`, noError,
+        `a = (1)`},
+    {"simple", `a = 1, b = ${a}`, noError,
+        `a = (1)b = (1)`},
+    {"nested path", `a { b { c = x } }, d = ${a.b}`, noError,
+        `a = (b = (c = (x)))d = (c = (x))`},
+    {"chain", `a = ${b}, b = ${c}, c = 3`, noError,
+        `a = (3)b = (3)c = (3)`},
+    {"string concatenation", `host = example.com, url = "http://"${host}":8080/"  index`, noError,
+        `host = (example.com)url = ("http://example.com:8080/  index")`},
+    {"object concatenation",
+        `generic { size = 10, name = generic }
+         east = ${generic} { name = east }`, noError,
+        `east = (name = (east)size = (10))generic = (name = (generic)size = (10))`},
+    {"object concatenation after object",
+        `generic { size = 10 }
+         east = { name = east } ${generic}`, noError,
+        `east = (name = (east)size = (10))generic = (size = (10))`},
+    {"list concatenation", `base = [a, b], path = ${base} [c] ${base}`, noError,
+        `base = (ab)path = (abcab)`},
+    {"object merged over substitution",
+        `generic { size = 10, name = generic }
+         east = ${generic}
+         east { name = east }`, noError,
+        `east = (name = (east)size = (10))generic = (name = (generic)size = (10))`},
+    {"inside lists", `a = 1, b = [${a}, ${a}]`, noError,
+        `a = (1)b = (11)`},
+    {"sibling reference", `a { b = 1, c = ${a.b} }`, noError,
+        `a = (b = (1)c = (1))`},
+    {"optional missing field", `a = ${?nowhere.to.be.found}, b = 1`, noError,
+        `b = (1)`},
+    {"optional missing in concatenation", `a = [1] ${?nowhere.to.be.found} [2]`, noError,
+        `a = (12)`},
+    {"object over earlier object", "b { y = 2 }\na { x = 1 }\na = ${b}", noError,
+        `a = (x = (1)y = (2))b = (y = (2))`},
+    {"concatenation over earlier object", "b { y = 2 }\na { x = 1 }\na = ${b} { z = 3 }", noError,
+        `a = (x = (1)y = (2)z = (3))b = (y = (2))`},
+    {"string over earlier object", "b = s\na { x = 1 }\na = ${b}", noError,
+        `a = (s)b = (s)`},
+    {"optional missing over earlier value", "a = 1\na = ${?nowhere.to.be.found}", noError,
+        `a = (1)`},
+    {"environment", `home = ${RESOLVE_TEST_HOME}/bin`, noError,
+        `home = ("/home/test/bin")`},
+    {"missing", `a = ${nowhere.to.be.found}`, hasError, ``},
+    {"cycle", `a = ${b}, b = ${a}`, hasError, ``},
+    {"ancestor cycle", `a { b = ${a} }`, hasError, ``},
+    {"object and string", `a { b = 1 }, c = ${a} foo`, hasError, ``},
+    {"list and object", `a = [1], c = ${a} { b = 1 }`, hasError, ``},
+}
+
+func TestResolve(t *testing.T) {
+    os.Setenv("RESOLVE_TEST_HOME", "/home/test")
+    defer os.Unsetenv("RESOLVE_TEST_HOME")
+    for _, test := range resolveTests {
+        tree, err := Parse(test.name, test.input)
+        if err != nil {
+            t.Errorf("%s: parse error: %v", test.name, err)
+            continue
+        }
+        conf := tree.GetConfig()
+        resolved, err := conf.Resolve()
+        switch {
+            case err == nil && !test.ok:
+            t.Errorf("%s: expected error; got none", test.name)
+            continue
+            case err != nil && test.ok:
+            t.Errorf("%s: unexpected error: %v", test.name, err)
+            continue
+            case err != nil && !test.ok:
+            if !strings.HasPrefix(err.Error(), test.name+":") {
+                t.Errorf("%s: error without position: %v", test.name, err)
+            }
+            continue
+        }
+        if result := resolved.String(); result != test.result {
+            t.Errorf("%s: got\n\t%v\nexpected\n\t%v", test.name, result, test.result)
+        }
+        if !resolved.IsResolved() {
+            t.Errorf("%s: not resolved", test.name)
+        }
+    }
+}
+
+func TestResolveLeavesOriginal(t *testing.T) {
+    tree, err := Parse("test", "a = 1, b = ${a}")
+    if err != nil {
+        t.Fatal(err)
+    }
+    conf := tree.GetConfig()
+    if _, err := conf.Resolve(); err != nil {
+        t.Fatal(err)
+    }
+    if conf.IsResolved() {
+        t.Errorf("Resolve changed the original config")
+    }
+    if _, err := conf.GetInt("b"); err == nil {
+        t.Errorf("expected an error getting an unresolved value")
+    }
+}
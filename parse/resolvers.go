@@ -0,0 +1,287 @@
+package parse
+
+import (
+    "context"
+    "fmt"
+    "io/ioutil"
+    "os"
+    "path/filepath"
+    "strings"
+    "sync"
+    "time"
+)
+
+// Resolver provides the values of the substitutions of a scheme, such as
+// ${env:HOME} or ${secret:vault/db#password}, so that secret stores and
+// other sources plug in to Resolve. Register it with RegisterResolver or
+// pass it in ResolveOptions.
+type Resolver interface {
+    // Lookup returns the value of ref, the text of the substitution after
+    // the scheme and its colon. It returns ErrMissing if there is no such
+    // value, which an optional substitution allows. It should give up
+    // when ctx is done.
+    Lookup(ctx context.Context, ref string) (string, error)
+}
+
+// SecretResolver is implemented by resolvers whose values are secrets:
+// Resolve marks them as WithSecrets does.
+type SecretResolver interface {
+    Resolver
+    Secret() bool
+}
+
+// ResolveOptions control how ResolveWith resolves substitutions and
+// decrypts values.
+type ResolveOptions struct {
+    // Resolvers by scheme, used over the registered ones.
+    Resolvers map[string]Resolver
+    // Timeout bounds each call to a resolver; zero means no limit.
+    Timeout time.Duration
+    // Context, if set, cancels the calls to resolvers.
+    Context context.Context
+    // Keyring decrypts the encrypted values; if nil, the keyring of the
+    // environment does, as given by KeyringFromEnv.
+    Keyring *Keyring
+    // Offline, if set, calls no resolvers and decrypts nothing, for tools
+    // such as editors that resolve configs as they are written: the
+    // substitutions of resolvers' schemes become empty strings, and
+    // encrypted values stay as they are.
+    Offline bool
+}
+
+var (
+    resolversMu sync.RWMutex
+    resolvers   = map[string]Resolver{"env": EnvResolver{}, "file": FileResolver{}}
+)
+
+// RegisterResolver makes r provide the substitutions of scheme for every
+// resolution, replacing any resolver registered for it before; a nil r
+// unregisters the scheme. The schemes env and file are registered at
+// start. A scheme is a letter followed by letters, digits, +, - or .;
+// RegisterResolver panics on any other.
+func RegisterResolver(scheme string, r Resolver) {
+    if !isScheme(scheme) {
+        panic("parse: bad resolver scheme " + scheme)
+    }
+    resolversMu.Lock()
+    defer resolversMu.Unlock()
+    if r == nil {
+        delete(resolvers, scheme)
+    } else {
+        resolvers[scheme] = r
+    }
+}
+
+func isScheme(s string) bool {
+    for i, c := range s {
+        letter := 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+        if !letter && (i == 0 || !('0' <= c && c <= '9' || c == '+' || c == '-' || c == '.')) {
+            return false
+        }
+    }
+    return s != ""
+}
+
+// splitScheme splits the path of a substitution into a scheme and a
+// reference, if it has the form scheme:ref.
+func splitScheme(path string) (scheme, ref string, ok bool) {
+    i := strings.Index(path, ":")
+    if i < 0 || !isScheme(path[:i]) {
+        return "", "", false
+    }
+    return path[:i], strings.TrimSpace(path[i+1:]), true
+}
+
+// external is the result of a call to a resolver.
+type external struct {
+    value  string
+    secret bool
+    err    error
+}
+
+// lookupExternal returns the value of ref from res, calling it once per
+// resolution.
+func (r *resolver) lookupExternal(s *SubstitutionNode, scheme, ref string, res Resolver) (Node, error) {
+    if f, ok := res.(FileResolver); ok && f.Dir == "" && !filepath.IsAbs(ref) && s.tr != nil {
+        ref = filepath.Join(s.tr.dir, ref)
+    }
+    key := scheme + ":" + ref
+    e, ok := r.externals[key]
+    if !ok {
+        if !r.opts.Offline {
+            e.value, e.err = r.call(res, ref)
+        }
+        if sr, ok := res.(SecretResolver); ok {
+            e.secret = sr.Secret()
+        }
+        r.externals[key] = e
+    }
+    switch {
+        case e.err == ErrMissing && s.Optional:
+            return nil, nil
+        case e.err == ErrMissing:
+            return nil, fmt.Errorf("%s: could not resolve substitution %s", location(s), s)
+        case e.err != nil:
+            return nil, fmt.Errorf("%s: substitution %s: %v", location(s), s, e.err)
+    }
+    n := s.tr.newString(s.Pos, quote(e.value), e.value)
+    n.Secret = e.secret
+    return n, nil
+}
+
+// call calls res, within the timeout of the resolution.
+func (r *resolver) call(res Resolver, ref string) (string, error) {
+    ctx := r.opts.Context
+    if ctx == nil {
+        ctx = context.Background()
+    }
+    if r.opts.Timeout > 0 {
+        var cancel context.CancelFunc
+        ctx, cancel = context.WithTimeout(ctx, r.opts.Timeout)
+        defer cancel()
+    }
+    if ctx.Done() == nil {
+        // It can never be done: there is nothing to wait for.
+        return res.Lookup(ctx, ref)
+    }
+    // A resolver that does not watch ctx is left behind when it is done.
+    done := make(chan external, 1)
+    go func() {
+        value, err := res.Lookup(ctx, ref)
+        done <- external{value: value, err: err}
+    }()
+    select {
+        case e := <-done:
+            return e.value, e.err
+        case <-ctx.Done():
+            return "", ctx.Err()
+    }
+}
+
+// EnvResolver provides the environment variables of the process:
+// ${env:HOME}. Unlike plain substitutions, which fall back to the
+// environment, these never read the config.
+type EnvResolver struct{}
+
+func (EnvResolver) Lookup(ctx context.Context, ref string) (string, error) {
+    if v, ok := os.LookupEnv(ref); ok {
+        return v, nil
+    }
+    return "", ErrMissing
+}
+
+// FileResolver provides the contents of files, without a final newline,
+// such as the secrets that containers get mounted: ${file:/run/secrets/db}.
+// Relative names are taken from Dir or, if Dir is empty, from the
+// directory of the file the substitution is in, as include does. The
+// values are secret.
+type FileResolver struct {
+    Dir string
+}
+
+func (f FileResolver) Lookup(ctx context.Context, ref string) (string, error) {
+    if !filepath.IsAbs(ref) && f.Dir != "" {
+        ref = filepath.Join(f.Dir, ref)
+    }
+    b, err := ioutil.ReadFile(ref)
+    if os.IsNotExist(err) {
+        return "", ErrMissing
+    }
+    if err != nil {
+        return "", err
+    }
+    s := strings.TrimSuffix(string(b), "\n")
+    return strings.TrimSuffix(s, "\r"), nil
+}
+
+func (FileResolver) Secret() bool {
+    return true
+}
+
+// MapResolver provides the values of a map, for tests and for values
+// computed in advance.
+type MapResolver map[string]string
+
+func (m MapResolver) Lookup(ctx context.Context, ref string) (string, error) {
+    if v, ok := m[ref]; ok {
+        return v, nil
+    }
+    return "", ErrMissing
+}
+
+// CachedResolver returns a resolver that remembers the values r provides
+// for ttl, or for ever if ttl is zero, so that repeated resolutions do
+// not call r again. Concurrent lookups of a ref that is not cached share
+// the result of a single call to r, errors included; errors are not
+// remembered after that. It is safe for concurrent use if r is, and its
+// values are secret if those of r are.
+func CachedResolver(r Resolver, ttl time.Duration) Resolver {
+    return &cachedResolver{r: r, ttl: ttl, values: make(map[string]cachedValue), calls: make(map[string]*cachedCall)}
+}
+
+type cachedResolver struct {
+    r      Resolver
+    ttl    time.Duration
+    mu     sync.Mutex // guards values and calls
+    values map[string]cachedValue
+    calls  map[string]*cachedCall // the calls to r in progress, by ref
+}
+
+type cachedValue struct {
+    value   string
+    expires time.Time // zero for ever
+}
+
+// cachedCall is a call to the resolver that other lookups of the same ref
+// wait for. Its value and err are set before done is closed.
+type cachedCall struct {
+    done  chan struct{}
+    value string
+    err   error
+}
+
+func (c *cachedResolver) Lookup(ctx context.Context, ref string) (string, error) {
+    c.mu.Lock()
+    v, ok := c.values[ref]
+    if ok && (v.expires.IsZero() || time.Now().Before(v.expires)) {
+        c.mu.Unlock()
+        return v.value, nil
+    }
+    call, ok := c.calls[ref]
+    if !ok {
+        call = &cachedCall{done: make(chan struct{})}
+        c.calls[ref] = call
+        c.mu.Unlock()
+        c.call(ctx, ref, call)
+        return call.value, call.err
+    }
+    c.mu.Unlock()
+    select {
+        case <-call.done:
+            return call.value, call.err
+        case <-ctx.Done():
+            return "", ctx.Err()
+    }
+}
+
+// call looks ref up for call and the lookups waiting on it, and caches
+// the value.
+func (c *cachedResolver) call(ctx context.Context, ref string, call *cachedCall) {
+    call.value, call.err = c.r.Lookup(ctx, ref)
+    c.mu.Lock()
+    delete(c.calls, ref)
+    if call.err == nil {
+        v := cachedValue{value: call.value}
+        if c.ttl > 0 {
+            v.expires = time.Now().Add(c.ttl)
+        }
+        c.values[ref] = v
+    }
+    c.mu.Unlock()
+    close(call.done)
+}
+
+func (c *cachedResolver) Secret() bool {
+    sr, ok := c.r.(SecretResolver)
+    return ok && sr.Secret()
+}
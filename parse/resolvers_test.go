@@ -0,0 +1,202 @@
+package parse
+
+import (
+    "context"
+    "io/ioutil"
+    "os"
+    "path/filepath"
+    "strings"
+    "sync"
+    "testing"
+    "time"
+)
+
+// countingResolver is a secret store that counts its lookups.
+type countingResolver struct {
+    mu    sync.Mutex
+    calls int
+    delay time.Duration
+}
+
+func (c *countingResolver) Lookup(ctx context.Context, ref string) (string, error) {
+    c.mu.Lock()
+    c.calls++
+    c.mu.Unlock()
+    if c.delay > 0 {
+        select {
+            case <-time.After(c.delay):
+            case <-ctx.Done():
+                return "", ctx.Err()
+        }
+    }
+    if ref == "missing" {
+        return "", ErrMissing
+    }
+    return "s3cr3t-" + ref, nil
+}
+
+func (c *countingResolver) Secret() bool {
+    return true
+}
+
+func TestResolveWith(t *testing.T) {
+    dir, err := ioutil.TempDir("", "resolvers")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer os.RemoveAll(dir)
+    if err := ioutil.WriteFile(filepath.Join(dir, "db"), []byte("filepw\n"), 0600); err != nil {
+        t.Fatal(err)
+    }
+    os.Setenv("RESOLVERS_TEST_HOME", "/home/test")
+    defer os.Unsetenv("RESOLVERS_TEST_HOME")
+
+    store := new(countingResolver)
+    opts := &ResolveOptions{Resolvers: map[string]Resolver{
+        "secret": store,
+        "file":   FileResolver{Dir: dir},
+        "mem":    MapResolver{"region": "eu"},
+    }}
+    conf, err := testConfig(t, `
+db.password = ${secret:vault/db#password}
+db.again = ${secret: vault/db#password}
+db.file = ${file:db}
+home = ${env:RESOLVERS_TEST_HOME}
+region = ${mem:region}
+url = "https://"${mem:region}".example.com"
+gone = ${?env:RESOLVERS_TEST_NOTHING}
+`).ResolveWith(opts)
+    if err != nil {
+        t.Fatal(err)
+    }
+    for path, expect := range map[string]string{
+        "db.password": "s3cr3t-vault/db#password", "db.again": "s3cr3t-vault/db#password", "db.file": "filepw",
+        "home": "/home/test", "region": "eu", "url": "https://eu.example.com",
+    } {
+        if got, err := conf.GetString(path); err != nil || got != expect {
+            t.Errorf("%s: got %q, %v, expected %q", path, got, err, expect)
+        }
+    }
+    if conf.HasPathOrNull("gone") {
+        t.Errorf("a missing optional substitution is kept")
+    }
+    if store.calls != 1 {
+        t.Errorf("the store was called %d times, expected once", store.calls)
+    }
+    for path, secret := range map[string]bool{"db.password": true, "db.file": true, "home": false, "region": false} {
+        if conf.IsSecret(path) != secret {
+            t.Errorf("IsSecret(%s) = %v", path, !secret)
+        }
+    }
+
+    errors := []struct {
+        text, err string
+    }{
+        {"a = ${secret:missing}", "test:1:5: could not resolve substitution ${secret:missing}"},
+        {"a = ${env:RESOLVERS_TEST_NOTHING}", "could not resolve substitution"},
+        {"a = ${nothing:x}", "could not resolve substitution ${nothing:x}"},
+        {"a = ${slow:x}", "test:1:5: substitution ${slow:x}: context deadline exceeded"},
+    }
+    opts.Resolvers["slow"] = &countingResolver{delay: time.Minute}
+    opts.Timeout = 10 * time.Millisecond
+    for _, test := range errors {
+        if _, err := testConfig(t, test.text).ResolveWith(opts); err == nil || !strings.Contains(err.Error(), test.err) {
+            t.Errorf("%s: got %v, expected %q", test.text, err, test.err)
+        }
+    }
+}
+
+func TestRegisterResolver(t *testing.T) {
+    store := new(countingResolver)
+    RegisterResolver("vault", CachedResolver(store, 0))
+    defer RegisterResolver("vault", nil)
+    for i := 0; i < 2; i++ {
+        conf, err := testConfig(t, "a = ${vault:db}").Resolve()
+        if err != nil {
+            t.Fatal(err)
+        }
+        if s, _ := conf.GetString("a"); s != "s3cr3t-db" || !conf.IsSecret("a") {
+            t.Errorf("got %q, secret %v", s, conf.IsSecret("a"))
+        }
+    }
+    if store.calls != 1 {
+        t.Errorf("the cached store was called %d times, expected once", store.calls)
+    }
+    defer func() {
+        if recover() == nil {
+            t.Errorf("expected a panic for a bad scheme")
+        }
+    }()
+    RegisterResolver("9p", store)
+}
+
+func TestFileResolverRelative(t *testing.T) {
+    dir := writeFiles(t, map[string]string{
+        "conf/app.conf":    `include "db/db.conf", key = ${file:secrets/key}`,
+        "conf/db/db.conf":  `db.password = ${file:password}`,
+        "conf/secrets/key": "k1\n",
+        "conf/db/password": "hunter2\n",
+    })
+    defer os.RemoveAll(dir)
+    tree, err := ParseFile(filepath.Join(dir, "conf", "app.conf"))
+    if err != nil {
+        t.Fatal(err)
+    }
+    conf, err := tree.GetConfig().Resolve()
+    if err != nil {
+        t.Fatal(err)
+    }
+    for path, expect := range map[string]string{"key": "k1", "db.password": "hunter2"} {
+        if s, err := conf.GetString(path); err != nil || s != expect {
+            t.Errorf("%s: got %q, %v, expected %q", path, s, err, expect)
+        }
+    }
+}
+
+func TestResolveOffline(t *testing.T) {
+    k, _ := testKeyring(t, "k1")
+    enc, err := k.Encrypt("c", "hunter2")
+    if err != nil {
+        t.Fatal(err)
+    }
+    store := new(countingResolver)
+    conf, err := testConfig(t, `a = ${secret:x}, b = ${file:/nothing}, c = `+quote(enc)+`, d = ${a}`).
+        ResolveWith(&ResolveOptions{Resolvers: map[string]Resolver{"secret": store}, Offline: true})
+    if err != nil {
+        t.Fatal(err)
+    }
+    if store.calls != 0 {
+        t.Errorf("the store was called %d times offline", store.calls)
+    }
+    for path, expect := range map[string]string{"a": "", "b": "", "c": enc, "d": ""} {
+        if s, err := conf.GetString(path); err != nil || s != expect {
+            t.Errorf("%s: got %q, %v, expected %q", path, s, err, expect)
+        }
+    }
+    if !conf.IsSecret("a") {
+        t.Errorf("the value of a secret store is not secret offline")
+    }
+}
+
+func TestCachedResolverConcurrent(t *testing.T) {
+    store := &countingResolver{delay: 50 * time.Millisecond}
+    cached := CachedResolver(store, 0)
+    var wg sync.WaitGroup
+    values := make([]string, 10)
+    for i := range values {
+        wg.Add(1)
+        go func(i int) {
+            defer wg.Done()
+            values[i], _ = cached.Lookup(context.Background(), "db")
+        }(i)
+    }
+    wg.Wait()
+    for _, v := range values {
+        if v != "s3cr3t-db" {
+            t.Errorf("got %q", v)
+        }
+    }
+    if store.calls != 1 {
+        t.Errorf("the store was called %d times by concurrent lookups, expected once", store.calls)
+    }
+}
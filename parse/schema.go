@@ -0,0 +1,368 @@
+package parse
+
+import (
+    "fmt"
+    "regexp"
+    "sort"
+    "strings"
+)
+
+// Schema describes the settings of a config, for Validate. A schema is
+// itself HOCON: each setting is described by an object with a type, and
+// any other object groups the settings below its key. For example:
+//
+//	akka.remote {
+//	  port { type = int, min = 1, max = 65535, required = true }
+//	  host { type = string, pattern = "[a-z0-9.-]+" }
+//	  timeout { type = duration, min = 1s, max = 1m }
+//	  transport { type = string, enum = [tcp, udp], deprecated = "use akka.remote.protocol" }
+//	}
+//
+// The attributes of a setting are:
+//
+//	type         string, int, number, boolean, duration, bytes, list,
+//	             object or any; the only attribute that must be given
+//	required     true if the setting must be present and not null
+//	min, max     inclusive bounds of an int, number, duration or bytes
+//	enum         the list of the values allowed
+//	pattern      a regular expression that a string must match as a whole
+//	deprecated   true, or a message, to report the setting when present
+//	description  what the setting is for
+//	elements     the description of the elements of a list
+//	fields       the descriptions of the settings of an object
+//	closed       true if an object may have no settings but its fields
+//
+// Values are converted as by the getters: a string that reads as a
+// number is a number, and so on. Settings that the schema does not
+// describe are allowed, except in closed objects.
+type Schema struct {
+    root *fieldSpec
+}
+
+// fieldSpec describes a setting, or a group of settings.
+type fieldSpec struct {
+    typ         string
+    group       bool // a plain object grouping settings
+    required    bool
+    min, max    Node
+    enum        []Node
+    pattern     *regexp.Regexp
+    deprecated  string // the message reporting the setting, if deprecated
+    description string
+    elements    *fieldSpec
+    fields      map[string]*fieldSpec
+    closed      bool
+}
+
+var schemaTypes = map[string]bool{
+    "string": true, "int": true, "number": true, "boolean": true, "duration": true,
+    "bytes": true, "list": true, "object": true, "any": true,
+}
+
+// ParseSchema parses and resolves text, the schema called name.
+func ParseSchema(name, text string) (*Schema, error) {
+    t, err := Parse(name, text)
+    if err != nil {
+        return nil, err
+    }
+    conf, err := t.GetConfig().Resolve()
+    if err != nil {
+        return nil, err
+    }
+    return NewSchema(conf)
+}
+
+// NewSchema returns the schema written in conf, which must be resolved.
+func NewSchema(conf *Config) (*Schema, error) {
+    if !conf.IsResolved() {
+        return nil, fmt.Errorf("NewSchema needs a resolved config")
+    }
+    if conf.root == nil {
+        return &Schema{root: &fieldSpec{typ: "object", group: true}}, nil
+    }
+    root, err := newFieldSpec(Path{}, conf.root)
+    if err != nil {
+        return nil, err
+    }
+    return &Schema{root: root}, nil
+}
+
+// newFieldSpec returns the description of the setting at path written in
+// n.
+func newFieldSpec(path Path, n Node) (*fieldSpec, error) {
+    bad := func(n Node, format string, args ...interface{}) error {
+        return fmt.Errorf("%s: %s: %s", location(n), path, fmt.Sprintf(format, args...))
+    }
+    m, ok := n.(*MapNode)
+    if !ok {
+        return nil, bad(n, "expected an object describing the setting, got %s", valueType(n))
+    }
+    s := &fieldSpec{fields: make(map[string]*fieldSpec)}
+    if t, ok := m.Nodes["type"].(*StringNode); !ok {
+        s.typ, s.group = "object", true
+        for _, k := range m.sortedKeys() {
+            field, err := newFieldSpec(path.child(k), m.Nodes[k])
+            if err != nil {
+                return nil, err
+            }
+            s.fields[k] = field
+        }
+        return s, nil
+    } else if !schemaTypes[t.Text] {
+        return nil, bad(t, "unknown type %q", t.Text)
+    } else {
+        s.typ = t.Text
+    }
+
+    var c Config
+    for _, k := range m.sortedKeys() {
+        v := m.Nodes[k]
+        var err error
+        switch k {
+            case "type":
+            case "required":
+                s.required, err = c.boolValue(v, k)
+            case "closed":
+                s.closed, err = c.boolValue(v, k)
+            case "min", "max":
+                if _, ok := s.bound(v); !ok {
+                    return nil, bad(v, "%s %s is not a %s", k, v, s.typ)
+                }
+                if k == "min" {
+                    s.min = v
+                } else {
+                    s.max = v
+                }
+            case "enum":
+                l, ok := v.(*ListNode)
+                if !ok {
+                    return nil, bad(v, "enum is not a list")
+                }
+                s.enum = l.Nodes
+            case "pattern":
+                var p string
+                if p, err = c.stringValue(v, k); err == nil {
+                    if s.pattern, err = regexp.Compile("^(?:" + p + ")$"); err != nil {
+                        return nil, bad(v, "pattern: %v", err)
+                    }
+                }
+            case "deprecated":
+                if b, ok := v.(*BoolNode); ok {
+                    if b.True {
+                        s.deprecated = "deprecated"
+                    }
+                } else {
+                    var msg string
+                    msg, err = c.stringValue(v, k)
+                    s.deprecated = "deprecated: " + msg
+                }
+            case "description":
+                s.description, err = c.stringValue(v, k)
+            case "elements":
+                if s.typ != "list" {
+                    return nil, bad(v, "elements of a %s", s.typ)
+                }
+                if s.elements, err = newFieldSpec(path.child(k), v); err != nil {
+                    return nil, err
+                }
+            case "fields":
+                f, ok := v.(*MapNode)
+                if !ok || s.typ != "object" {
+                    return nil, bad(v, "fields must be an object, of an object")
+                }
+                for _, name := range f.sortedKeys() {
+                    if s.fields[name], err = newFieldSpec(path.child(name), f.Nodes[name]); err != nil {
+                        return nil, err
+                    }
+                }
+            default:
+                return nil, bad(v, "unknown attribute %q", k)
+        }
+        if err != nil {
+            return nil, bad(v, "bad %s %s", k, v)
+        }
+    }
+    return s, nil
+}
+
+// bound returns the value n as a number to compare with the bounds of s,
+// which must have a numeric type.
+func (s *fieldSpec) bound(n Node) (float64, bool) {
+    var c Config
+    var err error
+    var f float64
+    switch s.typ {
+        case "int":
+            var i int64
+            i, err = c.intValue(n, "")
+            f = float64(i)
+        case "number":
+            f, err = c.floatValue(n, "")
+            if err != nil {
+                var i int64
+                i, err = c.intValue(n, "")
+                f = float64(i)
+            }
+        case "duration":
+            d, derr := c.durationValue(n, "")
+            f, err = float64(d), derr
+        case "bytes":
+            var b int64
+            b, err = c.bytesValue(n, "")
+            f = float64(b)
+        default:
+            return 0, false
+    }
+    return f, err == nil
+}
+
+// Description returns the description of the setting at path, or "" if
+// the schema has none.
+func (s *Schema) Description(path string) string {
+    segs, err := parsePath(path)
+    if err != nil {
+        return ""
+    }
+    spec := s.root
+    for _, seg := range segs {
+        if spec.elements != nil {
+            spec = spec.elements
+        } else if spec = spec.fields[seg]; spec == nil {
+            return ""
+        }
+    }
+    return spec.description
+}
+
+// Validate checks conf, which must be resolved, against schema, and
+// returns all the violations found as a ValidationErrors, sorted by path,
+// or nil.
+func Validate(conf *Config, schema *Schema) error {
+    if !conf.IsResolved() {
+        return fmt.Errorf("Validate needs a resolved config")
+    }
+    var errs ValidationErrors
+    conf.validate(&errs, Path{}, conf.root, conf.root, schema.root)
+    sort.SliceStable(errs, func(i, j int) bool {
+        return errs[i].Path.String() < errs[j].Path.String()
+    })
+    return errs.Err()
+}
+
+// validate checks the value n at path against s. The value is nil if it
+// is missing; parent is then the closest object above it that is there.
+func (c *Config) validate(errs *ValidationErrors, path Path, n, parent Node, s *fieldSpec) {
+    bad := func(format string, args ...interface{}) {
+        e := &ValidationError{Path: path, Msg: fmt.Sprintf(format, args...)}
+        if n != nil {
+            e.Origin = originOf(n)
+        }
+        *errs = append(*errs, e)
+    }
+    if n == nil || n.Type() == NodeNil {
+        if s.required {
+            msg := "missing"
+            if n != nil {
+                msg = "null"
+            } else {
+                n = parent
+            }
+            bad("%s, expected %s", msg, s.typ)
+            return
+        }
+        if s.group {
+            // The settings of a missing group may be required.
+            for _, k := range sortedFields(s) {
+                c.validate(errs, path.child(k), nil, parent, s.fields[k])
+            }
+        }
+        return
+    }
+    if s.deprecated != "" {
+        bad("%s", s.deprecated)
+    }
+    switch s.typ {
+        case "any":
+            return
+        case "object":
+            m, ok := n.(*MapNode)
+            if !ok {
+                bad("expected object, got %s", valueType(n))
+                return
+            }
+            for _, k := range sortedFields(s) {
+                c.validate(errs, path.child(k), m.Nodes[k], m, s.fields[k])
+            }
+            if s.closed {
+                for _, k := range m.sortedKeys() {
+                    if _, ok := s.fields[k]; !ok {
+                        *errs = append(*errs, &ValidationError{Path: path.child(k), Origin: originOf(m.Nodes[k]), Msg: "unknown setting"})
+                    }
+                }
+            }
+            return
+        case "list":
+            l, ok := n.(*ListNode)
+            if !ok {
+                bad("expected list, got %s", valueType(n))
+                return
+            }
+            if s.elements != nil {
+                for i, elem := range l.Nodes {
+                    c.validate(errs, path.child(fmt.Sprint(i)), elem, l, s.elements)
+                }
+            }
+            return
+    }
+
+    var err error
+    switch s.typ {
+        case "string":
+            _, err = c.stringValue(n, "")
+        case "boolean":
+            _, err = c.boolValue(n, "")
+        default:
+            if _, ok := s.bound(n); !ok {
+                err = fmt.Errorf("not a %s", s.typ)
+            }
+    }
+    if err != nil {
+        bad("expected %s, got %s", s.typ, valueType(n))
+        return
+    }
+    if v, ok := s.bound(n); ok {
+        if min, _ := s.bound(s.min); s.min != nil && v < min {
+            bad("%s is less than the minimum %s", n, s.min)
+        }
+        if max, _ := s.bound(s.max); s.max != nil && v > max {
+            bad("%s is more than the maximum %s", n, s.max)
+        }
+    }
+    if s.enum != nil {
+        text, _ := c.stringValue(n, "")
+        found := false
+        var allowed []string
+        for _, e := range s.enum {
+            t, _ := c.stringValue(e, "")
+            found = found || t == text
+            allowed = append(allowed, e.String())
+        }
+        if !found {
+            bad("%s is not one of %s", n, strings.Join(allowed, ", "))
+        }
+    }
+    if s.pattern != nil {
+        if text, _ := c.stringValue(n, ""); !s.pattern.MatchString(text) {
+            bad("%s does not match %s", n, strings.TrimSuffix(strings.TrimPrefix(s.pattern.String(), "^(?:"), ")$"))
+        }
+    }
+}
+
+func sortedFields(s *fieldSpec) []string {
+    keys := make([]string, 0, len(s.fields))
+    for k := range s.fields {
+        keys = append(keys, k)
+    }
+    sort.Strings(keys)
+    return keys
+}
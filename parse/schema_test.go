@@ -0,0 +1,107 @@
+package parse
+
+import (
+    "strings"
+    "testing"
+)
+
+const schemaText = `
+server {
+  port { type = int, min = 1, max = 65535, required = true, description = "the port to listen on" }
+  host { type = string, pattern = "[a-z0-9.-]+" }
+  timeout { type = duration, min = 1s, max = 1m }
+  mode { type = string, enum = [dev, prod] }
+  legacy { type = boolean, deprecated = "use server.mode" }
+  old { type = any, deprecated = true }
+}
+users {
+  type = list
+  elements {
+    type = object
+    closed = true
+    fields {
+      name { type = string, required = true }
+      quota { type = bytes, max = 1G }
+    }
+  }
+}
+db.url { type = string, required = true }
+`
+
+func TestValidate(t *testing.T) {
+    schema, err := ParseSchema("schema", schemaText)
+    if err != nil {
+        t.Fatal(err)
+    }
+    conf, err := testConfig(t, `db.url = "x", server { port = "8080", host = example.com, timeout = 30s, mode = prod }, users = [{name = a, quota = 10M}]`).Resolve()
+    if err != nil {
+        t.Fatal(err)
+    }
+    if err := Validate(conf, schema); err != nil {
+        t.Errorf("unexpected error: %v", err)
+    }
+
+    conf, err = testConfig(t, `server { port = 0, host = "Example.com", timeout = 2m, mode = test, legacy = yes, old = 1 }
+users = [{name = a, quota = 2G, extra = 1}, {}], extra = 1`).Resolve()
+    if err != nil {
+        t.Fatal(err)
+    }
+    err = Validate(conf, schema)
+    errs, ok := err.(ValidationErrors)
+    if !ok {
+        t.Fatalf("expected ValidationErrors, got %v", err)
+    }
+    var got []string
+    for _, e := range errs {
+        got = append(got, e.Error())
+    }
+    expect := []string{
+        "test:1:1: db.url: missing, expected string",
+        `test:1:27: server.host: "Example.com" does not match [a-z0-9.-]+`,
+        "test:1:78: server.legacy: deprecated: use server.mode",
+        "test:1:63: server.mode: test is not one of dev, prod",
+        "test:1:89: server.old: deprecated",
+        "test:1:17: server.port: 0 is less than the minimum 1",
+        "test:1:52: server.timeout: 2m is more than the maximum 1m",
+        "test:2:41: users.0.extra: unknown setting",
+        "test:2:29: users.0.quota: 2G is more than the maximum 1G",
+        "test:2:45: users.1.name: missing, expected string",
+    }
+    if strings.Join(got, "\n") != strings.Join(expect, "\n") {
+        t.Errorf("got\n\t%s\nexpected\n\t%s", strings.Join(got, "\n\t"), strings.Join(expect, "\n\t"))
+    }
+    if d := schema.Description("server.port"); d != "the port to listen on" {
+        t.Errorf("description: got %q", d)
+    }
+}
+
+func TestValidateEmpty(t *testing.T) {
+    schema, err := ParseSchema("schema", "a.b { type = int, required = true }")
+    if err != nil {
+        t.Fatal(err)
+    }
+    empty, err := testConfig(t, "").Resolve()
+    if err != nil {
+        t.Fatal(err)
+    }
+    for _, conf := range []*Config{empty, new(Config)} {
+        if err := Validate(conf, schema); err == nil || !strings.HasSuffix(err.Error(), "a.b: missing, expected int") {
+            t.Errorf("got %v, expected a.b to be missing", err)
+        }
+    }
+}
+
+func TestParseSchemaError(t *testing.T) {
+    for _, text := range []string{
+        `a { type = integer }`,
+        `a { type = int, min = x }`,
+        `a { type = string, size = 1 }`,
+        `a { type = string, elements { type = int } }`,
+        `a = 1`,
+        `a { type = string, pattern = "(" }`,
+    } {
+        if _, err := ParseSchema("schema", text); err == nil {
+            t.Errorf("%s: expected an error", text)
+        }
+    }
+}
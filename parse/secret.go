@@ -0,0 +1,205 @@
+package parse
+
+import (
+    "fmt"
+    "path"
+    "strings"
+)
+
+// Redacted replaces the values of secrets wherever a config is printed:
+// in the String methods of configs and nodes, in the renderers, and so in
+// the errors and changes that show values.
+const Redacted = "<redacted>"
+
+// SecretKeyPatterns match the settings whose key names suggest a secret,
+// such as db.password or github-token. Pass them to WithSecrets, with any
+// patterns of your own.
+var SecretKeyPatterns = []string{
+    "**.*password*", "**.*passwd*", "**.*secret*", "**.*token*",
+    "**.*apikey*", "**.*api-key*", "**.*api_key*", "**.*credential*",
+    "**.*private-key*", "**.*private_key*",
+}
+
+// WithSecrets returns a copy of c with the values at the paths matching
+// any of patterns marked as secret. A pattern is a path whose segments
+// are matched with path.Match, regardless of case, and where ** matches
+// any number of segments: "db.password", "**.*token*" and
+// "services.*.credentials". A secret object or list makes every value in
+// it secret.
+//
+// Secret values read as usual with the getters and GetSecret, but are
+// redacted whenever they are printed. The marks go with the values
+// through WithFallback and Resolve, and to the values substituted for
+// secret substitutions, so that patterns may be applied before resolving
+// as well as after: but values that c does not have yet, such as those of
+// a fallback merged in later, are not marked.
+func (c *Config) WithSecrets(patterns ...string) (*Config, error) {
+    var pats [][]string
+    for _, p := range patterns {
+        segs, err := parsePath(p)
+        if err != nil {
+            return nil, err
+        }
+        for i, seg := range segs {
+            if _, err := path.Match(seg, ""); err != nil {
+                return nil, fmt.Errorf("secret pattern %q: %v", p, err)
+            }
+            segs[i] = strings.ToLower(seg)
+        }
+        pats = append(pats, segs)
+    }
+    if c.root == nil || len(pats) == 0 {
+        return c, nil
+    }
+    root := c.root.Copy()
+    markSecrets(Path{}, root, pats)
+    return c.wrap(root), nil
+}
+
+// markSecrets marks the values at path n and below that match any of
+// pats. It changes n, which must be private to the caller.
+func markSecrets(p Path, n Node, pats [][]string) {
+    for _, pat := range pats {
+        if matchPath(pat, p) {
+            markSecret(n)
+            return
+        }
+    }
+    switch v := n.(type) {
+        case *MapNode:
+            for k, child := range v.Nodes {
+                markSecrets(p.child(k), child, pats)
+            }
+        case *ListNode:
+            for i, elem := range v.Nodes {
+                markSecrets(p.child(fmt.Sprint(i)), elem, pats)
+            }
+    }
+}
+
+// matchPath reports whether the path p matches the pattern pat.
+func matchPath(pat []string, p Path) bool {
+    if len(pat) == 0 {
+        return len(p) == 0
+    }
+    if pat[0] == "**" {
+        for i := 0; i <= len(p); i++ {
+            if matchPath(pat[1:], p[i:]) {
+                return true
+            }
+        }
+        return false
+    }
+    if len(p) == 0 {
+        return false
+    }
+    ok, _ := path.Match(pat[0], strings.ToLower(p[0]))
+    return ok && matchPath(pat[1:], p[1:])
+}
+
+// markSecret marks n and every value below it as secret. It changes n,
+// which must be private to the caller.
+func markSecret(n Node) {
+    switch v := n.(type) {
+        case *MapNode:
+            for _, child := range v.Nodes {
+                markSecret(child)
+            }
+        case *ListNode:
+            for _, elem := range v.Nodes {
+                markSecret(elem)
+            }
+        case *StringNode:
+            v.Secret = true
+        case *NumberNode:
+            v.Secret = true
+        case *BoolNode:
+            v.Secret = true
+        case *SubstitutionNode:
+            v.Secret = true
+        case *ConcatNode:
+            v.Secret = true
+    }
+}
+
+// isSecret reports whether the value n is redacted when printed. The
+// path of a substitution is not a secret, only the value substituted.
+func isSecret(n Node) bool {
+    switch v := n.(type) {
+        case *StringNode:
+            return v.Secret
+        case *NumberNode:
+            return v.Secret
+        case *BoolNode:
+            return v.Secret
+        case *ConcatNode:
+            return v.Secret
+    }
+    return false
+}
+
+// IsSecret reports whether the value at path is secret, or is an object
+// or list holding secrets.
+func (c *Config) IsSecret(path string) bool {
+    conf, err := c.GetValue(path)
+    return err == nil && hasSecret(conf.root)
+}
+
+func hasSecret(n Node) bool {
+    switch v := n.(type) {
+        case *MapNode:
+            for _, child := range v.Nodes {
+                if hasSecret(child) {
+                    return true
+                }
+            }
+        case *ListNode:
+            for _, elem := range v.Nodes {
+                if hasSecret(elem) {
+                    return true
+                }
+            }
+        case *SubstitutionNode:
+            return v.Secret
+    }
+    return isSecret(n)
+}
+
+// Secret holds a secret string. It prints as Redacted, with fmt as with
+// the encoders of encoding/json and others that use MarshalText, so that
+// a struct holding one may be logged; Value returns the secret itself.
+type Secret struct {
+    value string
+}
+
+// NewSecret returns the secret s.
+func NewSecret(s string) Secret {
+    return Secret{s}
+}
+
+// Value returns the secret.
+func (s Secret) Value() string {
+    return s.value
+}
+
+func (s Secret) String() string {
+    return Redacted
+}
+
+func (s Secret) GoString() string {
+    return "parse.Secret{" + Redacted + "}"
+}
+
+func (s Secret) MarshalText() ([]byte, error) {
+    return []byte(Redacted), nil
+}
+
+// GetSecret returns the string at path as a Secret, whether or not it is
+// marked as one.
+func (c *Config) GetSecret(path string) (val Secret, err error) {
+    s, err := c.GetString(path)
+    if err != nil {
+        return
+    }
+    return Secret{s}, nil
+}
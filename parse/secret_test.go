@@ -0,0 +1,97 @@
+package parse
+
+import (
+    "encoding/json"
+    "fmt"
+    "strings"
+    "testing"
+)
+
+const secretInput = `
+db { user = app, password = hunter2, url = "pg://"${db.user}":"${db.password}"@db" }
+github-token = 12345
+alias = ${db.password}
+services = [{ name = a, credentials { key = k1, enabled = true } }]
+port = 80
+`
+
+func TestSecrets(t *testing.T) {
+    conf, err := testConfig(t, secretInput).WithSecrets(SecretKeyPatterns...)
+    if err != nil {
+        t.Fatal(err)
+    }
+    // Marked before resolving, so that substitutions inherit the marks.
+    conf, err = conf.Resolve()
+    if err != nil {
+        t.Fatal(err)
+    }
+    leaks := []string{"hunter2", "12345", "k1", "true"}
+    outputs := []string{conf.String()}
+    for _, format := range []Format{FormatHOCON, FormatJSON, FormatYAML} {
+        out, err := conf.Render(format)
+        if err != nil {
+            t.Fatal(err)
+        }
+        outputs = append(outputs, string(out))
+    }
+    for _, out := range outputs {
+        for _, s := range leaks {
+            if strings.Contains(out, s) {
+                t.Errorf("%q leaks %s", out, s)
+            }
+        }
+        if !strings.Contains(out, Redacted) || !strings.Contains(out, "80") {
+            t.Errorf("%q: expected redacted secrets and plain values", out)
+        }
+    }
+
+    for path, secret := range map[string]bool{
+        "db.password": true, "db.url": true, "alias": true, "github-token": true,
+        "services.0.credentials": true, "services.0.name": false, "db": true, "db.user": false, "port": false,
+    } {
+        if conf.IsSecret(path) != secret {
+            t.Errorf("IsSecret(%s) = %v", path, !secret)
+        }
+    }
+    if s, err := conf.GetString("db.url"); err != nil || s != "pg://app:hunter2@db" {
+        t.Errorf("GetString: %q, %v", s, err)
+    }
+    if b, err := conf.GetBool("services.0.credentials.enabled"); err != nil || !b {
+        t.Errorf("GetBool: %v, %v", b, err)
+    }
+    s, err := conf.GetSecret("alias")
+    if err != nil || s.Value() != "hunter2" {
+        t.Errorf("GetSecret: %q, %v", s.Value(), err)
+    }
+    logged := struct {
+        Password Secret
+    }{s}
+    out, _ := json.Marshal(logged)
+    for _, text := range []string{fmt.Sprint(s), fmt.Sprintf("%q %+v %#v", s, logged, logged), string(out)} {
+        if strings.Contains(text, "hunter2") {
+            t.Errorf("%s leaks the secret", text)
+        }
+    }
+
+    // Errors and changes show values redacted too.
+    schema, err := ParseSchema("schema", "github-token { type = int, max = 100 }")
+    if err != nil {
+        t.Fatal(err)
+    }
+    if err := Validate(conf, schema); err == nil || strings.Contains(err.Error(), "12345") {
+        t.Errorf("Validate: %v", err)
+    }
+    other, _ := testConfig(t, "db.password = swordfish").WithSecrets("DB.Password")
+    unmarked := testConfig(t, "db.password = swordfish, services = [{ credentials { key = k2 } }]")
+    for _, c := range append(Diff(conf, other), Diff(conf, unmarked)...) {
+        if strings.Contains(c.String(), "hunter2") || strings.Contains(c.String(), "swordfish") || strings.Contains(c.String(), "k2") {
+            t.Errorf("change %s leaks a secret", c)
+        }
+    }
+    if !conf.Equal(conf.WithFallback(other)) {
+        t.Errorf("a config with secrets differs from itself")
+    }
+    if _, err := conf.WithSecrets(`a\`); err == nil {
+        t.Errorf("expected an error for a bad pattern")
+    }
+}
@@ -0,0 +1,105 @@
+package parse
+
+import (
+    "fmt"
+    "math"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// durationUnits maps the HOCON duration unit names to their length.
+var durationUnits = map[string]time.Duration{
+    "ns": time.Nanosecond, "nano": time.Nanosecond, "nanos": time.Nanosecond,
+    "nanosecond": time.Nanosecond, "nanoseconds": time.Nanosecond,
+    "us": time.Microsecond, "micro": time.Microsecond, "micros": time.Microsecond,
+    "microsecond": time.Microsecond, "microseconds": time.Microsecond,
+    "ms": time.Millisecond, "milli": time.Millisecond, "millis": time.Millisecond,
+    "millisecond": time.Millisecond, "milliseconds": time.Millisecond,
+    "": time.Millisecond,
+    "s": time.Second, "second": time.Second, "seconds": time.Second,
+    "m": time.Minute, "minute": time.Minute, "minutes": time.Minute,
+    "h": time.Hour, "hour": time.Hour, "hours": time.Hour,
+    "d": 24 * time.Hour, "day": 24 * time.Hour, "days": 24 * time.Hour,
+}
+
+// byteUnits maps the HOCON size unit names to their size in bytes. Single
+// letters and the "i" forms are powers of two, the "B" forms powers of ten.
+var byteUnits = map[string]float64{}
+
+func init() {
+    byteUnits[""] = 1
+    for _, s := range []string{"B", "b", "byte", "bytes"} {
+        byteUnits[s] = 1
+    }
+    powers := []struct {
+        letter, si, iec string
+    }{
+        {"K", "kilo", "kibi"},
+        {"M", "mega", "mebi"},
+        {"G", "giga", "gibi"},
+        {"T", "tera", "tebi"},
+        {"P", "peta", "pebi"},
+        {"E", "exa", "exbi"},
+        {"Z", "zetta", "zebi"},
+        {"Y", "yotta", "yobi"},
+    }
+    for i, p := range powers {
+        binary := math.Pow(1024, float64(i+1))
+        decimal := math.Pow(1000, float64(i+1))
+        for _, s := range []string{p.letter, strings.ToLower(p.letter), p.letter + "i", p.letter + "iB", p.iec + "byte", p.iec + "bytes"} {
+            byteUnits[s] = binary
+        }
+        for _, s := range []string{p.letter + "B", p.si + "byte", p.si + "bytes"} {
+            byteUnits[s] = decimal
+        }
+    }
+    byteUnits["kB"] = 1000
+}
+
+// splitUnit splits a string such as "100 s" into its number and unit.
+func splitUnit(s string) (num, unit string) {
+    s = strings.TrimSpace(s)
+    i := strings.LastIndexAny(s, "0123456789.") + 1
+    return strings.TrimSpace(s[:i]), strings.TrimSpace(s[i:])
+}
+
+// parseDuration parses a HOCON duration: a number of milliseconds, or a
+// number followed by a unit such as "ns", "ms", "s", "minutes" or "d".
+func parseDuration(s string) (time.Duration, error) {
+    num, unit := splitUnit(s)
+    d, ok := durationUnits[unit]
+    if !ok {
+        return 0, fmt.Errorf("unknown duration unit %q in %q", unit, s)
+    }
+    if i, err := strconv.ParseInt(num, 10, 64); err == nil {
+        return time.Duration(i) * d, nil
+    }
+    f, err := strconv.ParseFloat(num, 64)
+    if err != nil {
+        return 0, fmt.Errorf("bad duration %q", s)
+    }
+    return time.Duration(f * float64(d)), nil
+}
+
+// parseBytes parses a HOCON size in bytes: a number of bytes, or a number
+// followed by a unit such as "K", "KiB", "kB", "megabytes" or "G".
+func parseBytes(s string) (int64, error) {
+    num, unit := splitUnit(s)
+    size, ok := byteUnits[unit]
+    if !ok {
+        return 0, fmt.Errorf("unknown size unit %q in %q", unit, s)
+    }
+    if i, err := strconv.ParseInt(num, 10, 64); err == nil && size == 1 {
+        return i, nil
+    }
+    f, err := strconv.ParseFloat(num, 64)
+    if err != nil {
+        return 0, fmt.Errorf("bad size %q", s)
+    }
+    f *= size
+    if f >= math.MaxInt64 || f < math.MinInt64 {
+        return 0, fmt.Errorf("size %q out of range", s)
+    }
+    return int64(f), nil
+}
@@ -0,0 +1,122 @@
+package parse
+
+import (
+    "fmt"
+)
+
+// ValidationError is a value of a config that does not match what is
+// expected of it.
+type ValidationError struct {
+    Path   Path
+    Origin Origin // where the value, or its parent if missing, was defined
+    Msg    string
+}
+
+func (e *ValidationError) Error() string {
+    if e.Origin.Name == "" {
+        return fmt.Sprintf("%s: %s", e.Path, e.Msg)
+    }
+    return fmt.Sprintf("%s: %s: %s", e.Origin, e.Path, e.Msg)
+}
+
+// ValidationErrors is the list of problems found by a validation, sorted
+// by path.
+type ValidationErrors []*ValidationError
+
+func (l ValidationErrors) Error() string {
+    switch len(l) {
+        case 0:
+            return "no errors"
+        case 1:
+            return l[0].Error()
+    }
+    return fmt.Sprintf("%s (and %d more errors)", l[0], len(l)-1)
+}
+
+// Err returns an error equivalent to this error list.
+// If the list is empty, Err returns nil.
+func (l ValidationErrors) Err() error {
+    if len(l) == 0 {
+        return nil
+    }
+    return l
+}
+
+// CheckValid checks c against a reference config, which holds the default
+// value of every setting: each path of reference must be present in c
+// with a value of a compatible type. Strings, numbers and booleans are
+// compatible when the getters would convert between them; a null in
+// reference accepts any value or none. Both configs must be resolved. The result
+// is nil or a ValidationErrors.
+func (c *Config) CheckValid(reference *Config) error {
+    if !c.IsResolved() || !reference.IsResolved() {
+        return fmt.Errorf("CheckValid needs resolved configs")
+    }
+    var errs ValidationErrors
+    c.checkValid(&errs, Path{}, c.root, reference.root)
+    return errs.Err()
+}
+
+func (c *Config) checkValid(errs *ValidationErrors, path Path, n, ref Node) {
+    bad := func(format string, args ...interface{}) {
+        *errs = append(*errs, &ValidationError{Path: path, Origin: originOf(n), Msg: fmt.Sprintf(format, args...)})
+    }
+    switch r := ref.(type) {
+        case nil, *NilNode:
+            return
+        case *MapNode:
+            m, ok := n.(*MapNode)
+            if !ok {
+                bad("expected object, got %s", valueType(n))
+                return
+            }
+            for _, k := range r.sortedKeys() {
+                child, ok := m.Nodes[k]
+                if !ok && r.Nodes[k].Type() == NodeNil {
+                    continue
+                }
+                if !ok {
+                    *errs = append(*errs, &ValidationError{Path: path.child(k), Origin: originOf(m), Msg: "missing, expected " + valueType(r.Nodes[k])})
+                    continue
+                }
+                c.checkValid(errs, path.child(k), child, r.Nodes[k])
+            }
+            return
+        case *ListNode:
+            if n.Type() != NodeList {
+                bad("expected list, got %s", valueType(n))
+            }
+            return
+    }
+    var ok bool
+    switch ref.Type() {
+        case NodeNumber:
+            _, ok = c.numberValue(n)
+        case NodeBool:
+            _, err := c.boolValue(n, "")
+            ok = err == nil
+        default:
+            _, err := c.stringValue(n, "")
+            ok = err == nil
+    }
+    if !ok {
+        bad("expected %s, got %s", valueType(ref), valueType(n))
+    }
+}
+
+// valueType names the type of n for messages.
+func valueType(n Node) string {
+    switch n.Type() {
+        case NodeMap:
+            return "object"
+        case NodeList:
+            return "list"
+        case NodeNumber:
+            return "number"
+        case NodeBool:
+            return "boolean"
+        case NodeNil:
+            return "null"
+    }
+    return "string"
+}
@@ -0,0 +1,123 @@
+package parse
+
+import (
+    "errors"
+    "strconv"
+    "strings"
+)
+
+// Path is the sequence of keys leading from the root of a config to one of
+// its values. List elements are named by their index.
+type Path []string
+
+// String returns p in the form accepted by GetValue: segments separated by
+// dots, quoted when they are empty or contain characters that would not
+// survive as a path segment.
+func (p Path) String() string {
+    segs := make([]string, len(p))
+    for i, seg := range p {
+        if needsQuote(seg) {
+            seg = strconv.Quote(seg)
+        }
+        segs[i] = seg
+    }
+    return strings.Join(segs, ".")
+}
+
+func needsQuote(seg string) bool {
+    if seg == "" || strings.Contains(seg, "//") {
+        return true
+    }
+    for _, r := range seg {
+        if r == '.' || !isUnquoted(r) {
+            return true
+        }
+    }
+    return false
+}
+
+// child returns a copy of p extended by key, so that paths handed to a
+// WalkFunc stay valid after it returns.
+func (p Path) child(key string) Path {
+    q := make(Path, len(p)+1)
+    copy(q, p)
+    q[len(p)] = key
+    return q
+}
+
+// SkipSubtree is used as a return value from a WalkFunc to indicate that
+// the children of the value visited are to be skipped. It is not returned
+// as an error by Walk.
+var SkipSubtree = errors.New("skip this subtree")
+
+// StopWalk is used as a return value from a WalkFunc to end the walk
+// early. It is not returned as an error by Walk.
+var StopWalk = errors.New("stop the walk")
+
+// WalkFunc is the type of the function called by Walk for each value.
+// If it returns an error the walk stops and Walk returns that error, apart
+// from SkipSubtree and StopWalk.
+type WalkFunc func(path Path, value *Config) error
+
+// Walk calls fn for every value in c, starting with c itself at the empty
+// path, parents before their children. Object keys are visited in sorted
+// order and list elements in index order.
+func (c *Config) Walk(fn WalkFunc) error {
+    if c.root == nil {
+        return nil
+    }
+    err := c.walk(Path{}, c.root, fn)
+    if err == StopWalk {
+        err = nil
+    }
+    return err
+}
+
+func (c *Config) walk(path Path, n Node, fn WalkFunc) error {
+    err := fn(path, c.wrap(n))
+    if err == SkipSubtree {
+        return nil
+    }
+    if err != nil {
+        return err
+    }
+    switch v := n.(type) {
+        case *MapNode:
+            for _, k := range v.sortedKeys() {
+                if err := c.walk(path.child(k), v.Nodes[k], fn); err != nil {
+                    return err
+                }
+            }
+        case *ListNode:
+            for i, elem := range v.Nodes {
+                if err := c.walk(path.child(strconv.Itoa(i)), elem, fn); err != nil {
+                    return err
+                }
+            }
+    }
+    return nil
+}
+
+// Entry is a path and the value found there, as returned by Entries.
+type Entry struct {
+    Path  Path
+    Value *Config
+}
+
+// Entries returns every value of c that is not an object, sorted by path.
+// Lists are entries of their own and are not expanded; null values and
+// empty objects are left out.
+func (c *Config) Entries() []Entry {
+    var entries []Entry
+    c.Walk(func(path Path, value *Config) error {
+        switch value.root.Type() {
+            case NodeMap:
+                return nil
+            case NodeNil:
+                return SkipSubtree
+        }
+        entries = append(entries, Entry{path, value})
+        return SkipSubtree
+    })
+    return entries
+}
@@ -0,0 +1,97 @@
+package parse
+
+import (
+    "errors"
+    "strings"
+    "testing"
+)
+
+func TestPathString(t *testing.T) {
+    tests := []struct {
+        path   Path
+        result string
+    }{
+        {Path{}, ""},
+        {Path{"akka", "cluster", "seed-nodes", "0"}, "akka.cluster.seed-nodes.0"},
+        {Path{"akka", "a.b"}, `akka."a.b"`},
+        {Path{"", "a b"}, `""."a b"`},
+    }
+    for _, test := range tests {
+        if s := test.path.String(); s != test.result {
+            t.Errorf("%q: got %s, expected %s", []string(test.path), s, test.result)
+        }
+    }
+}
+
+func TestWalk(t *testing.T) {
+    conf := testConfig(t, configInput)
+    var visited []string
+    err := conf.Walk(func(path Path, value *Config) error {
+        visited = append(visited, path.String())
+        if path.String() == "akka.cluster.roles" {
+            return SkipSubtree
+        }
+        return nil
+    })
+    if err != nil {
+        t.Fatal(err)
+    }
+    expect := []string{
+        "",
+        "akka",
+        `akka."a.b"`,
+        "akka.cluster",
+        "akka.cluster.roles",
+        "akka.cluster.seed-nodes",
+        "akka.cluster.seed-nodes.0",
+        "akka.cluster.seed-nodes.1",
+        "akka.loglevel",
+    }
+    if got := strings.Join(visited, " "); got != strings.Join(expect, " ") {
+        t.Errorf("got\n\t%s\nexpected\n\t%s", got, strings.Join(expect, " "))
+    }
+    // Every path leads back to its value.
+    conf.Walk(func(path Path, value *Config) error {
+        v, err := conf.GetValue(path.String())
+        if err != nil {
+            t.Errorf("%s: %v", path, err)
+        } else if v.String() != value.String() {
+            t.Errorf("%s: got %s, expected %s", path, v, value)
+        }
+        return nil
+    })
+}
+
+func TestWalkStop(t *testing.T) {
+    conf := testConfig(t, configInput)
+    n := 0
+    err := conf.Walk(func(path Path, value *Config) error {
+        n++
+        if len(path) == 2 {
+            return StopWalk
+        }
+        return nil
+    })
+    if err != nil || n != 3 {
+        t.Errorf("got %d visits and error %v, expected 3 and none", n, err)
+    }
+    bad := errors.New("bad")
+    err = conf.Walk(func(path Path, value *Config) error {
+        return bad
+    })
+    if err != bad {
+        t.Errorf("got error %v, expected %v", err, bad)
+    }
+}
+
+func TestEntries(t *testing.T) {
+    conf := testConfig(t, `b { c = 1, d = null, e {} }, a = [1, {x = 2}], "q.r" = s`)
+    var got []string
+    for _, e := range conf.Entries() {
+        got = append(got, e.Path.String()+"="+e.Value.String())
+    }
+    expect := `a=1x = (2) b.c=1 "q.r"=s`
+    if s := strings.Join(got, " "); s != expect {
+        t.Errorf("got\n\t%s\nexpected\n\t%s", s, expect)
+    }
+}
@@ -0,0 +1,189 @@
+package parse
+
+import (
+    "errors"
+    "os"
+    "sync"
+    "time"
+)
+
+// Watcher keeps a config loaded from a set of files up to date. It polls
+// the files, and the files they include, for changes in size or
+// modification time; when one changes it parses and resolves them again
+// and, if the result is valid and differs from the current config,
+// publishes it to its subscribers. When a reload fails the current config
+// is kept.
+//
+// Set Validate and OnError before calling Start.
+type Watcher struct {
+    // Validate, if set, checks a newly loaded config before it replaces
+    // the previous one, which is nil on the first load. A non-nil error
+    // rejects the new config.
+    Validate func(next, prev *Config) error
+    // OnError, if set, is called with the errors of reloads made by the
+    // polling loop.
+    OnError func(err error)
+
+    files    []string
+    interval time.Duration
+
+    current AtomicConfig
+    mu      sync.Mutex // held during a reload; guards stamps
+    stamps  map[string]fileStamp
+    subMu   sync.Mutex // guards subs
+    subs    []func(conf *Config, changes []Change)
+    stop    chan struct{}
+    done    chan struct{}
+    closed  sync.Once
+}
+
+// fileStamp is what the watcher remembers of a file to notice changes.
+type fileStamp struct {
+    exists  bool
+    size    int64
+    modTime time.Time
+}
+
+func stampOf(path string) fileStamp {
+    fi, err := os.Stat(path)
+    if err != nil {
+        return fileStamp{}
+    }
+    return fileStamp{exists: true, size: fi.Size(), modTime: fi.ModTime()}
+}
+
+// NewWatcher returns a watcher for the given files, checked every interval.
+// The config is the files merged in order, values in later files taking
+// precedence over those in earlier ones.
+func NewWatcher(interval time.Duration, files ...string) *Watcher {
+    return &Watcher{files: files, interval: interval}
+}
+
+// Start loads the config and starts polling for changes. It returns an
+// error, and does not start, if the first load fails.
+func (w *Watcher) Start() error {
+    if w.stop != nil {
+        return errors.New("watcher already started")
+    }
+    if err := w.Reload(); err != nil {
+        return err
+    }
+    w.stop = make(chan struct{})
+    w.done = make(chan struct{})
+    go w.poll()
+    return nil
+}
+
+// Close stops polling. It waits for a reload in progress to finish.
+// Closing a watcher again does nothing.
+func (w *Watcher) Close() {
+    if w.stop == nil {
+        return
+    }
+    w.closed.Do(func() { close(w.stop) })
+    <-w.done
+}
+
+func (w *Watcher) poll() {
+    defer close(w.done)
+    ticker := time.NewTicker(w.interval)
+    defer ticker.Stop()
+    for {
+        select {
+            case <-w.stop:
+                return
+            case <-ticker.C:
+                if !w.changed() {
+                    continue
+                }
+                if err := w.Reload(); err != nil && w.OnError != nil {
+                    w.OnError(err)
+                }
+        }
+    }
+}
+
+// Config returns the current config, or nil before the first successful
+// load.
+func (w *Watcher) Config() *Config {
+    return w.current.Load()
+}
+
+// Subscribe registers fn to be called with each new config and its
+// differences from the previous one. Calls are made one at a time, from
+// the goroutine doing the reload; fn must not call Reload or Subscribe.
+func (w *Watcher) Subscribe(fn func(conf *Config, changes []Change)) {
+    w.subMu.Lock()
+    defer w.subMu.Unlock()
+    w.subs = append(w.subs, fn)
+}
+
+// changed reports whether any watched file changed since the last load.
+func (w *Watcher) changed() bool {
+    w.mu.Lock()
+    defer w.mu.Unlock()
+    for path, stamp := range w.stamps {
+        if stampOf(path) != stamp {
+            return true
+        }
+    }
+    return false
+}
+
+// Reload loads the files now, whether they changed or not, and publishes
+// the result if it is valid and differs from the current config.
+func (w *Watcher) Reload() error {
+    w.mu.Lock()
+    defer w.mu.Unlock()
+    stamps := make(map[string]fileStamp)
+    var conf *Config
+    for _, file := range w.files {
+        stamps[file] = stampOf(file)
+        tree, err := ParseFile(file)
+        if tree != nil {
+            for _, inc := range tree.Includes {
+                stamps[inc] = stampOf(inc)
+            }
+        }
+        if err != nil {
+            // Watch what was read so far, so that fixing it triggers
+            // another reload.
+            w.stamps = stamps
+            return err
+        }
+        if conf == nil {
+            conf = tree.GetConfig()
+        } else {
+            conf = conf.wrap(tree.Root.withFallback(conf.root))
+        }
+    }
+    w.stamps = stamps
+    if conf == nil {
+        conf = &Config{root: New("").newMap(0)}
+    }
+    conf, err := conf.Resolve()
+    if err != nil {
+        return err
+    }
+    prev := w.Config()
+    if w.Validate != nil {
+        if err := w.Validate(conf, prev); err != nil {
+            return err
+        }
+    }
+    if prev == nil {
+        w.current.Store(conf)
+        return nil
+    }
+    changes := Diff(prev, conf)
+    if len(changes) == 0 {
+        return nil
+    }
+    w.current.Store(conf)
+    w.subMu.Lock()
+    defer w.subMu.Unlock()
+    for _, fn := range w.subs {
+        fn(conf, changes)
+    }
+    return nil
+}
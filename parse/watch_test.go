@@ -0,0 +1,162 @@
+package parse
+
+import (
+    "errors"
+    "io/ioutil"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+    "time"
+)
+
+func TestWatcherReload(t *testing.T) {
+    dir := writeFiles(t, map[string]string{
+        "base.conf":     `a = 1, b = 1, include "included.conf"`,
+        "included.conf": `c = 1`,
+        "local.conf":    `b = 2`,
+    })
+    defer os.RemoveAll(dir)
+    write := func(name, text string) {
+        if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(text), 0644); err != nil {
+            t.Fatal(err)
+        }
+    }
+    w := NewWatcher(time.Hour, filepath.Join(dir, "base.conf"), filepath.Join(dir, "local.conf"))
+    w.Validate = func(next, prev *Config) error {
+        if !next.HasPath("a") {
+            return errors.New("a is required")
+        }
+        return nil
+    }
+    var updates [][]Change
+    w.Subscribe(func(conf *Config, changes []Change) {
+        updates = append(updates, changes)
+    })
+    if err := w.Reload(); err != nil {
+        t.Fatal(err)
+    }
+    if s := w.Config().String(); s != `a = (1)b = (2)c = (1)` {
+        t.Fatalf("first load: got %s", s)
+    }
+    if len(updates) != 0 {
+        t.Errorf("first load published %v", updates)
+    }
+
+    write("included.conf", `c = 2`)
+    if !w.changed() {
+        t.Errorf("change to an included file not seen")
+    }
+    if err := w.Reload(); err != nil {
+        t.Fatal(err)
+    }
+    if len(updates) != 1 || len(updates[0]) != 1 || updates[0][0].Path.String() != "c" {
+        t.Errorf("got updates %v, expected a change of c", updates)
+    }
+
+    // A broken or invalid config is not published.
+    good := w.Config()
+    write("base.conf", `a = `)
+    if err := w.Reload(); err == nil {
+        t.Errorf("expected a parse error")
+    }
+    write("base.conf", `b = 3`)
+    if err := w.Reload(); err == nil {
+        t.Errorf("expected a validation error")
+    }
+    if w.Config() != good || len(updates) != 1 {
+        t.Errorf("bad config published: %s", w.Config())
+    }
+
+    // Going back to the current values publishes nothing.
+    write("base.conf", `a = 1, b = 1, include "included.conf"`)
+    if err := w.Reload(); err != nil {
+        t.Fatal(err)
+    }
+    if len(updates) != 1 {
+        t.Errorf("got %d updates, expected 1", len(updates))
+    }
+}
+
+func TestWatcherResolve(t *testing.T) {
+    dir := writeFiles(t, map[string]string{
+        "base.conf":  `host = db, url = "pg://"${host}"/app"`,
+        "local.conf": `host = db2`,
+    })
+    defer os.RemoveAll(dir)
+    w := NewWatcher(time.Hour, filepath.Join(dir, "base.conf"), filepath.Join(dir, "local.conf"))
+    var changes []Change
+    w.Subscribe(func(conf *Config, c []Change) {
+        changes = c
+    })
+    if err := w.Reload(); err != nil {
+        t.Fatal(err)
+    }
+    if s, err := w.Config().GetString("url"); err != nil || s != "pg://db2/app" {
+        t.Errorf("url: got %q, %v", s, err)
+    }
+
+    if err := ioutil.WriteFile(filepath.Join(dir, "local.conf"), []byte(`host = db3`), 0644); err != nil {
+        t.Fatal(err)
+    }
+    if err := w.Reload(); err != nil {
+        t.Fatal(err)
+    }
+    if len(changes) != 2 || changes[1].Path.String() != "url" || changes[1].New.String() != `"pg://db3/app"` {
+        t.Errorf("got changes %v", changes)
+    }
+
+    // A substitution that cannot be resolved fails like a parse error.
+    good := w.Config()
+    if err := ioutil.WriteFile(filepath.Join(dir, "local.conf"), []byte(`host = ${nothing}`), 0644); err != nil {
+        t.Fatal(err)
+    }
+    if err := w.Reload(); err == nil || !strings.Contains(err.Error(), "could not resolve substitution") {
+        t.Errorf("got %v, expected a resolve error", err)
+    }
+    if w.Config() != good {
+        t.Errorf("unresolved config published: %s", w.Config())
+    }
+}
+
+func TestWatcherPoll(t *testing.T) {
+    dir := writeFiles(t, map[string]string{"app.conf": `a = 1`})
+    defer os.RemoveAll(dir)
+    w := NewWatcher(10*time.Millisecond, filepath.Join(dir, "app.conf"))
+    published := make(chan *Config, 1)
+    w.Subscribe(func(conf *Config, changes []Change) {
+        published <- conf
+    })
+    if err := w.Start(); err != nil {
+        t.Fatal(err)
+    }
+    defer w.Close()
+    // A different size makes the change visible whatever the resolution
+    // of modification times.
+    if err := ioutil.WriteFile(filepath.Join(dir, "app.conf"), []byte(`a = 22`), 0644); err != nil {
+        t.Fatal(err)
+    }
+    select {
+        case conf := <-published:
+            if a, _ := conf.GetInt("a"); a != 22 {
+                t.Errorf("got a = %d, expected 22", a)
+            }
+            if w.Config() != conf {
+                t.Errorf("Config is not the published config")
+            }
+        case <-time.After(5 * time.Second):
+            t.Fatal("change not published")
+    }
+}
+
+func TestWatcherCloseTwice(t *testing.T) {
+    dir := writeFiles(t, map[string]string{"app.conf": `a = 1`})
+    defer os.RemoveAll(dir)
+    w := NewWatcher(time.Hour, filepath.Join(dir, "app.conf"))
+    w.Close()
+    if err := w.Start(); err != nil {
+        t.Fatal(err)
+    }
+    w.Close()
+    w.Close()
+}